@@ -29,10 +29,13 @@ func main() {
 	start := time.Now()
 
 	var backend_type string
+	var dsn string
 	var AWSDefaultRegion string
 	var AWSAccessKeyId string
 	var AWSSecretAccessKey string
 	var DynamoDBUrl string
+	var MongoDBUri string
+	var MongoDBDatabaseName string
 	var basepath string
 	var table_name string
 	var recursive bool
@@ -41,11 +44,14 @@ func main() {
 	var version bool
 	var help bool
 
-	flag.StringVar(&backend_type, "backend", "dynamodb", "NoSQL backend type: dynamodb or mongodb.")
+	flag.StringVar(&backend_type, "backend", "dynamodb", "NoSQL backend type: "+strings.Join(nosql.Backends(), ", ")+".")
 	flag.StringVar(&AWSDefaultRegion, "aws_default_region", os.Getenv("AWS_DEFAULT_REGION"), "Defaults to value of environment variable AWS_DEFAULT_REGION.")
 	flag.StringVar(&AWSAccessKeyId, "aws_access_key_id", os.Getenv("AWS_ACCESS_KEY_ID"), "Defaults to value of environment variable AWS_ACCESS_KEY_ID")
 	flag.StringVar(&AWSSecretAccessKey, "aws_secret_access_key", os.Getenv("AWS_SECRET_ACCESS_KEY"), "Defaults to value of environment variable AWS_SECRET_ACCESS_KEY.")
+	flag.StringVar(&dsn, "dsn", "", "Connection DSN, e.g., dynamodb://us-west-2 or mongodb://localhost/main.  Overrides the other connection flags.")
 	flag.StringVar(&DynamoDBUrl, "dynamodb_url", "", "Defaults to AWS instance.")
+	flag.StringVar(&MongoDBUri, "mongodb_uri", "localhost", "MongoDB URI.")
+	flag.StringVar(&MongoDBDatabaseName, "mongodb_database", "", "MongoDB database name.")
 	flag.StringVar(&basepath, "basepath", "", "Path to folder containing objects to import.")
 	flag.StringVar(&table_name, "table", "", "Table name.")
 	flag.BoolVar(&recursive, "recursive", false, "Recursive search within folder.")
@@ -103,18 +109,39 @@ func main() {
 		os.Exit(1)
 	}
 
-	backend, err := nosql.ConnectToBackend(backend_type, map[string]string{
-		"AWSDefaultRegion":   AWSDefaultRegion,
-		"AWSAccessKeyId":     AWSAccessKeyId,
-		"AWSSecretAccessKey": AWSSecretAccessKey,
-		"DynamoDBUrl":        DynamoDBUrl,
-	})
+	var backend nosql.Backend
+	if len(dsn) > 0 {
+		backend, err = nosql.Open(dsn)
+	} else {
+		options := map[string]string{}
+		switch backend_type {
+		case "dynamodb":
+			options = map[string]string{
+				"AWSDefaultRegion":   AWSDefaultRegion,
+				"AWSAccessKeyId":     AWSAccessKeyId,
+				"AWSSecretAccessKey": AWSSecretAccessKey,
+				"DynamoDBUrl":        DynamoDBUrl,
+			}
+		case "mongodb":
+			options = map[string]string{
+				"DatabaseUri":  MongoDBUri,
+				"DatabaseName": MongoDBDatabaseName,
+			}
+		}
+		var b *nosql.Backend
+		b, err = nosql.ConnectToBackend(backend_type, options)
+		if err == nil {
+			backend = *b
+		}
+	}
 	if err != nil {
 		log.Println(chalk.Red, err, chalk.Reset)
 		os.Exit(1)
 	}
 
 	if len(filepaths) > 0 {
+		objects := make([]map[string]interface{}, 0, len(filepaths))
+		paths := make([]string, 0, len(filepaths))
 		for _, f := range filepaths {
 			buf := make([]byte, 0)
 			buf, err := ioutil.ReadFile(f)
@@ -141,11 +168,19 @@ func main() {
 				}
 			}
 
-			err = (*backend).InsertItem(table_name, &newObject)
-			if err != nil {
-				log.Println(chalk.Red, err, chalk.Reset)
-			}
+			objects = append(objects, newObject)
+			paths = append(paths, f)
+		}
 
+		results, err := backend.InsertItems(table_name, objects)
+		for _, r := range results {
+			if r.Err != nil {
+				log.Println(chalk.Red, "Error: Could not import object from path", paths[r.Index], ".", chalk.Reset)
+				log.Println(chalk.Red, "Original Error:", r.Err, chalk.Reset)
+			}
+		}
+		if err != nil && results == nil {
+			log.Println(chalk.Red, err, chalk.Reset)
 		}
 	}
 
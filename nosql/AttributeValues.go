@@ -0,0 +1,222 @@
+package nosql
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+import (
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// attributeValueRank orders the types of DynamoDB attribute values when comparing
+// values of different types.  Missing and null values sort first, like in MongoDB.
+func attributeValueRank(av *dynamodb.AttributeValue) int {
+	switch {
+	case av == nil || av.NULL != nil:
+		return 0
+	case av.N != nil:
+		return 1
+	case av.S != nil:
+		return 2
+	case av.M != nil:
+		return 3
+	case av.L != nil:
+		return 4
+	case av.B != nil:
+		return 5
+	case av.BOOL != nil:
+		return 6
+	case av.SS != nil:
+		return 7
+	case av.NS != nil:
+		return 8
+	case av.BS != nil:
+		return 9
+	}
+	return 0
+}
+
+// compareAttributeValues returns -1, 0, or 1 depending on whether a is less than,
+// equal to, or greater than b.  Numbers are compared numerically, strings and binary
+// values lexicographically, and values of different types by type.  Maps and sets
+// are only compared by type.
+func compareAttributeValues(a *dynamodb.AttributeValue, b *dynamodb.AttributeValue) int {
+	ra, rb := attributeValueRank(a), attributeValueRank(b)
+	if ra != rb {
+		if ra < rb {
+			return -1
+		}
+		return 1
+	}
+	switch ra {
+	case 1:
+		return compareNumbers(*a.N, *b.N)
+	case 2:
+		return strings.Compare(*a.S, *b.S)
+	case 4:
+		for i := 0; i < len(a.L) && i < len(b.L); i++ {
+			if c := compareAttributeValues(a.L[i], b.L[i]); c != 0 {
+				return c
+			}
+		}
+		return compareInts(len(a.L), len(b.L))
+	case 5:
+		return bytes.Compare(a.B, b.B)
+	case 6:
+		if *a.BOOL == *b.BOOL {
+			return 0
+		}
+		if !*a.BOOL {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+// equalAttributeValues returns true if a and b are the same value.  Sets are equal
+// if they contain the same elements in any order.
+func equalAttributeValues(a *dynamodb.AttributeValue, b *dynamodb.AttributeValue) bool {
+	ra, rb := attributeValueRank(a), attributeValueRank(b)
+	if ra != rb {
+		return false
+	}
+	switch ra {
+	case 0:
+		return (a == nil) == (b == nil)
+	case 3:
+		if len(a.M) != len(b.M) {
+			return false
+		}
+		for k, v := range a.M {
+			if !equalAttributeValues(v, b.M[k]) {
+				return false
+			}
+		}
+		return true
+	case 4:
+		if len(a.L) != len(b.L) {
+			return false
+		}
+		for i := range a.L {
+			if !equalAttributeValues(a.L[i], b.L[i]) {
+				return false
+			}
+		}
+		return true
+	case 7:
+		return equalSets(len(a.SS), len(b.SS), func(i, j int) bool { return *a.SS[i] == *b.SS[j] })
+	case 8:
+		return equalSets(len(a.NS), len(b.NS), func(i, j int) bool { return compareNumbers(*a.NS[i], *b.NS[j]) == 0 })
+	case 9:
+		return equalSets(len(a.BS), len(b.BS), func(i, j int) bool { return bytes.Equal(a.BS[i], b.BS[j]) })
+	}
+	return compareAttributeValues(a, b) == 0
+}
+
+// equalSets returns true if every element of a is equal to some element of b and the
+// sets have the same size.
+func equalSets(na int, nb int, equal func(i, j int) bool) bool {
+	if na != nb {
+		return false
+	}
+	for i := 0; i < na; i++ {
+		found := false
+		for j := 0; j < nb; j++ {
+			if equal(i, j) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func compareNumbers(a string, b string) int {
+	x, _, errx := big.ParseFloat(a, 10, 128, big.ToNearestEven)
+	y, _, erry := big.ParseFloat(b, 10, 128, big.ToNearestEven)
+	if errx != nil || erry != nil {
+		return strings.Compare(a, b)
+	}
+	return x.Cmp(y)
+}
+
+// addNumbers returns the exact sum of two DynamoDB numbers.
+func addNumbers(a string, b string) (string, error) {
+	x, ok := new(big.Rat).SetString(a)
+	if !ok {
+		return "", &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("%q is not a number", a)}
+	}
+	y, ok := new(big.Rat).SetString(b)
+	if !ok {
+		return "", &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("%q is not a number", b)}
+	}
+	sum := x.Add(x, y)
+	if sum.IsInt() {
+		return sum.Num().String(), nil
+	}
+	// DynamoDB numbers have up to 38 significant digits.
+	return strings.TrimRight(sum.FloatString(38), "0"), nil
+}
+
+func compareInts(a int, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// attributeValueAtPath returns the value of a possibly nested attribute, or nil.
+func attributeValueAtPath(item map[string]*dynamodb.AttributeValue, attribute_name string) *dynamodb.AttributeValue {
+	parts := strings.Split(attribute_name, ".")
+	av := item[parts[0]]
+	for _, part := range parts[1:] {
+		if av == nil || av.M == nil {
+			return nil
+		}
+		av = av.M[part]
+	}
+	return av
+}
+
+// sortAttributeValueMaps sorts items in place by the sort fields, using the MongoDB
+// syntax where a leading "-" sorts in descending order.
+func sortAttributeValueMaps(items []map[string]*dynamodb.AttributeValue, sort_fields []string) {
+	if len(sort_fields) == 0 {
+		return
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		for _, f := range sort_fields {
+			descending := strings.HasPrefix(f, "-")
+			attribute_name := strings.TrimPrefix(strings.TrimPrefix(f, "-"), "+")
+			c := compareAttributeValues(attributeValueAtPath(items[i], attribute_name), attributeValueAtPath(items[j], attribute_name))
+			if c != 0 {
+				if descending {
+					return c > 0
+				}
+				return c < 0
+			}
+		}
+		return false
+	})
+}
+
+// itemId returns the id of a marshaled item.
+func itemId(item map[string]*dynamodb.AttributeValue) (string, error) {
+	av, ok := item["id"]
+	if !ok || av.S == nil || len(*av.S) == 0 {
+		return "", &Error{Kind: ErrInvalidQuery, Err: errors.New("item is missing a string id attribute")}
+	}
+	return *av.S, nil
+}
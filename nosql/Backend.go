@@ -1,21 +1,51 @@
 package nosql
 
 type Backend interface {
+	BackendContext
 	Type() string
 	Connect(map[string]string) error
+	DefineTables(tables []Table) error
 	CreateTables(tables []Table) error
 	CreateTable(table_name string, indexes []string, readUnits int, writeUnits int) error
+	UpdateThroughput(table_name string, throughput *Throughput) error
+	UpdateTimeToLive(table_name string, ttl_attribute string) error
 	DeleteTables(table_names []string) error
 	DeleteTable(table_name string) error
+	ListTables() ([]string, error)
+	DescribeTable(table_name string) (*TableDescription, error)
+	TableExists(table_name string) (bool, error)
 	GetItems(table_name string, index_name string, sort_fields []string, item interface{}) error
 	GetItemById(table_name string, id string, item interface{}) error
+	GetItem(table_name string, key Key, item interface{}) error
 	GetItemsByIds(table_name string, ids []string, sort_fields []string, items interface{}) error
 	GetItemByAttributeValue(table_name string, attribute_name string, attribute_value string, item interface{}) error
 	GetItemsByAttributeValue(table_name string, attribute_name string, attribute_value string, sort_fields []string, items interface{}) error
+	GetItemByAttribute(table_name string, attribute_name string, value interface{}, item interface{}) error
+	GetItemsByAttribute(table_name string, attribute_name string, value interface{}, sort_fields []string, items interface{}) error
+	GetItemsPage(table_name string, index_name string, sort_fields []string, page_size int, token string, items interface{}) (string, error)
+	GetItemsByAttributeValuePage(table_name string, attribute_name string, attribute_value string, sort_fields []string, page_size int, token string, items interface{}) (string, error)
+	Scan(table_name string, index_name string, sort_fields []string) Iterator
+	Query(table_name string, attribute_name string, attribute_value string, sort_fields []string) Iterator
+	QueryItems(table_name string, query *KeyQuery, items interface{}) error
+	Find(table_name string, filter Filter, opts *FindOptions, items interface{}) error
+	Count(table_name string, filter Filter) (int64, error)
+	Exists(table_name string, id string) (bool, error)
+	// InsertItem and InsertItems overwrite existing items on DynamoDB and in memory, but
+	// fail with ErrConflict on MongoDB.  Use InsertItemIfNotExists to fail everywhere.
 	InsertItem(table_name string, item interface{}) error
+	InsertItemIfNotExists(table_name string, item interface{}) error
+	ReplaceItem(table_name string, item interface{}) error
+	UpsertItem(table_name string, item interface{}) error
+	InsertItems(table_name string, items interface{}) ([]BulkResult, error)
+	PutItems(table_name string, items interface{}) ([]BulkResult, error)
 	UpdateItemById(table_name string, id string, item map[string]interface{}) error
+	UpdateItem(table_name string, id string, update *Update) error
+	WriteTransaction(tx *Transaction) error
+	ReadTransaction(reads []TransactionRead) error
 	RemoveItemById(table_name string, id string) error
+	RemoveItem(table_name string, key Key) error
 	RemoveItemByAttributeValue(table_name string, attribute_name string, attribute_value string) error
 	RemoveItemsByAttributeValue(table_name string, attribute_name string, attribute_value string) error
 	RemoveAll(table_name string) error
+	DeleteItemsByIds(table_name string, ids []string) ([]BulkResult, error)
 }
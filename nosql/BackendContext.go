@@ -0,0 +1,51 @@
+package nosql
+
+import (
+	"context"
+)
+
+// BackendContext is the context-aware variant of Backend.  Each method honors
+// cancellation and deadlines of the given context.
+type BackendContext interface {
+	CreateTablesContext(ctx context.Context, tables []Table) error
+	CreateTableContext(ctx context.Context, table_name string, indexes []string, readUnits int, writeUnits int) error
+	UpdateThroughputContext(ctx context.Context, table_name string, throughput *Throughput) error
+	UpdateTimeToLiveContext(ctx context.Context, table_name string, ttl_attribute string) error
+	DeleteTablesContext(ctx context.Context, table_names []string) error
+	DeleteTableContext(ctx context.Context, table_name string) error
+	ListTablesContext(ctx context.Context) ([]string, error)
+	DescribeTableContext(ctx context.Context, table_name string) (*TableDescription, error)
+	TableExistsContext(ctx context.Context, table_name string) (bool, error)
+	GetItemsContext(ctx context.Context, table_name string, index_name string, sort_fields []string, item interface{}) error
+	GetItemByIdContext(ctx context.Context, table_name string, id string, item interface{}) error
+	GetItemContext(ctx context.Context, table_name string, key Key, item interface{}) error
+	GetItemsByIdsContext(ctx context.Context, table_name string, ids []string, sort_fields []string, items interface{}) error
+	GetItemByAttributeValueContext(ctx context.Context, table_name string, attribute_name string, attribute_value string, item interface{}) error
+	GetItemsByAttributeValueContext(ctx context.Context, table_name string, attribute_name string, attribute_value string, sort_fields []string, items interface{}) error
+	GetItemByAttributeContext(ctx context.Context, table_name string, attribute_name string, value interface{}, item interface{}) error
+	GetItemsByAttributeContext(ctx context.Context, table_name string, attribute_name string, value interface{}, sort_fields []string, items interface{}) error
+	GetItemsPageContext(ctx context.Context, table_name string, index_name string, sort_fields []string, page_size int, token string, items interface{}) (string, error)
+	GetItemsByAttributeValuePageContext(ctx context.Context, table_name string, attribute_name string, attribute_value string, sort_fields []string, page_size int, token string, items interface{}) (string, error)
+	ScanContext(ctx context.Context, table_name string, index_name string, sort_fields []string) Iterator
+	QueryContext(ctx context.Context, table_name string, attribute_name string, attribute_value string, sort_fields []string) Iterator
+	QueryItemsContext(ctx context.Context, table_name string, query *KeyQuery, items interface{}) error
+	FindContext(ctx context.Context, table_name string, filter Filter, opts *FindOptions, items interface{}) error
+	CountContext(ctx context.Context, table_name string, filter Filter) (int64, error)
+	ExistsContext(ctx context.Context, table_name string, id string) (bool, error)
+	InsertItemContext(ctx context.Context, table_name string, item interface{}) error
+	InsertItemIfNotExistsContext(ctx context.Context, table_name string, item interface{}) error
+	ReplaceItemContext(ctx context.Context, table_name string, item interface{}) error
+	UpsertItemContext(ctx context.Context, table_name string, item interface{}) error
+	InsertItemsContext(ctx context.Context, table_name string, items interface{}) ([]BulkResult, error)
+	PutItemsContext(ctx context.Context, table_name string, items interface{}) ([]BulkResult, error)
+	UpdateItemByIdContext(ctx context.Context, table_name string, id string, item map[string]interface{}) error
+	UpdateItemContext(ctx context.Context, table_name string, id string, update *Update) error
+	WriteTransactionContext(ctx context.Context, tx *Transaction) error
+	ReadTransactionContext(ctx context.Context, reads []TransactionRead) error
+	RemoveItemByIdContext(ctx context.Context, table_name string, id string) error
+	RemoveItemContext(ctx context.Context, table_name string, key Key) error
+	RemoveItemByAttributeValueContext(ctx context.Context, table_name string, attribute_name string, attribute_value string) error
+	RemoveItemsByAttributeValueContext(ctx context.Context, table_name string, attribute_name string, attribute_value string) error
+	RemoveAllContext(ctx context.Context, table_name string) error
+	DeleteItemsByIdsContext(ctx context.Context, table_name string, ids []string) ([]BulkResult, error)
+}
@@ -1,10 +1,13 @@
 package nosql
 
 import (
-	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"log"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -12,6 +15,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
@@ -19,6 +23,47 @@ import (
 
 type BackendDynamoDB struct {
 	dynamodb_client *dynamodb.DynamoDB
+	keys_mutex      sync.Mutex
+	keys            map[string]*keysDynamoDB
+	definitions     tableDefinitions
+	wait_for_active bool
+	wait_timeout    time.Duration
+}
+
+// batchGetItemLimitDynamoDB is the maximum number of keys in a BatchGetItem request.
+const batchGetItemLimitDynamoDB = 100
+
+// batchWriteItemLimitDynamoDB is the maximum number of items in a BatchWriteItem request.
+const batchWriteItemLimitDynamoDB = 25
+
+// keysDynamoDB is the key schema of a table and of its secondary indexes.
+type keysDynamoDB struct {
+	HashKey  string
+	RangeKey string
+	Indexes  map[string]*keysDynamoDB
+}
+
+func init() {
+	Register("dynamodb", func(options map[string]string) (Backend, error) {
+		b := &BackendDynamoDB{}
+		err := b.Connect(options)
+		if err != nil {
+			return nil, err
+		}
+		return b, nil
+	})
+	RegisterScheme("dynamodb", func(dsn string) (Backend, error) {
+		config, err := ParseConfigDynamoDB(dsn)
+		if err != nil {
+			return nil, err
+		}
+		b := &BackendDynamoDB{}
+		err = b.ConnectWithConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		return b, nil
+	})
 }
 
 func (b *BackendDynamoDB) Type() string {
@@ -26,33 +71,68 @@ func (b *BackendDynamoDB) Type() string {
 }
 
 func (b *BackendDynamoDB) Connect(options map[string]string) error {
+	config, err := NewConfigDynamoDB(options)
+	if err != nil {
+		return err
+	}
+	return b.ConnectWithConfig(config)
+}
+
+func (b *BackendDynamoDB) ConnectWithConfig(config *ConfigDynamoDB) error {
+
+	err := config.Validate()
+	if err != nil {
+		return err
+	}
 
-	aws_access_key_id := options["AWSAccessKeyId"]
-	aws_secret_access_key := options["AWSSecretAccessKey"]
-	//aws_session_token := options["AWSSessionToken"]
-	aws_region := options["AWSDefaultRegion"]
-	dynamodb_url := options["DynamoDBUrl"]
+	aws_access_key_id := config.AccessKeyId
+	aws_secret_access_key := config.SecretAccessKey
+	aws_session_token := config.SessionToken
 
-	if strings.Contains(dynamodb_url, "localhost") {
+	if strings.Contains(config.Endpoint, "localhost") && len(aws_access_key_id) == 0 {
 		aws_access_key_id = "localhost"
 		aws_secret_access_key = "localhost"
-		//aws_session_token = "localhost"
+		aws_session_token = ""
 	}
 
-	aws_session := session.Must(session.NewSessionWithOptions(session.Options{
-		Config: aws.Config{
-			Credentials: credentials.NewStaticCredentials(aws_access_key_id, aws_secret_access_key, ""),
-			Endpoint:    aws.String(dynamodb_url),
-			MaxRetries:  aws.Int(3),
-			Region:      aws.String(aws_region),
-		},
-	}))
+	aws_config := aws.Config{
+		MaxRetries: aws.Int(config.MaxRetries),
+		Region:     aws.String(config.Region),
+	}
+	if len(aws_access_key_id) > 0 {
+		aws_config.Credentials = credentials.NewStaticCredentials(aws_access_key_id, aws_secret_access_key, aws_session_token)
+	}
+	if len(config.Endpoint) > 0 {
+		aws_config.Endpoint = aws.String(config.Endpoint)
+	}
+
+	aws_session, err := session.NewSessionWithOptions(session.Options{
+		Config: aws_config,
+	})
+	if err != nil {
+		return err
+	}
 
 	b.dynamodb_client = dynamodb.New(aws_session)
+	b.wait_for_active = config.WaitForActive
+	b.wait_timeout = config.WaitTimeout
+	if b.wait_timeout == 0 {
+		b.wait_timeout = defaultWaitTimeout
+	}
+	b.definitions.defineDefaultVersionAttribute(config.VersionAttribute)
 	return nil
 }
 
 func (b *BackendDynamoDB) GetItemById(table_name string, id string, item interface{}) error {
+	return b.GetItemByIdContext(context.Background(), table_name, id, item)
+}
+
+func (b *BackendDynamoDB) GetItemByIdContext(ctx context.Context, table_name string, id string, item interface{}) error {
+	if err := b.requireIdKey(ctx, table_name); err != nil {
+		return err
+	}
+	b.defineTimeToLive(ctx, table_name)
+
 	input := &dynamodb.GetItemInput{
 		TableName: aws.String(table_name),
 		Key: map[string]*dynamodb.AttributeValue{
@@ -62,12 +142,16 @@ func (b *BackendDynamoDB) GetItemById(table_name string, id string, item interfa
 		},
 	}
 
-	result, err := b.dynamodb_client.GetItem(input)
+	result, err := b.dynamodb_client.GetItemWithContext(ctx, input)
 	if err != nil {
-		return err
+		return wrapDynamoDBError(err)
+	}
+
+	if len(result.Item) == 0 || b.expired(table_name, result.Item) {
+		return ErrNotFound
 	}
 
-	err = dynamodbattribute.UnmarshalMap(result.Item, item)
+	err = dynamodbattribute.UnmarshalMap(unmarshalExpiry(result.Item, b.definitions.ttlAttribute(table_name)), item)
 	if err != nil {
 		return err
 	}
@@ -75,23 +159,33 @@ func (b *BackendDynamoDB) GetItemById(table_name string, id string, item interfa
 	return nil
 }
 
-func (b *BackendDynamoDB) GetItemsByIds(table_name string, ids []string, sort_fields []string, items interface{}) error {
-	input := &dynamodb.QueryInput{
-		TableName: aws.String(table_name),
-		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
-			"id": {
-				SS: aws.StringSlice(ids),
-			},
-		},
-		KeyConditionExpression: aws.String("id IN :id"),
-	}
+func (b *BackendDynamoDB) GetItem(table_name string, key Key, item interface{}) error {
+	return b.GetItemContext(context.Background(), table_name, key, item)
+}
+
+// GetItemContext reads the item with the key, which must match the key schema of the
+// table.
+func (b *BackendDynamoDB) GetItemContext(ctx context.Context, table_name string, key Key, item interface{}) error {
+	b.defineTimeToLive(ctx, table_name)
 
-	result, err := b.dynamodb_client.Query(input)
+	av, err := b.key(ctx, table_name, key)
 	if err != nil {
 		return err
 	}
 
-	err = dynamodbattribute.UnmarshalListOfMaps(result.Items, items)
+	result, err := b.dynamodb_client.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(table_name),
+		Key:       av,
+	})
+	if err != nil {
+		return wrapDynamoDBError(err)
+	}
+
+	if len(result.Item) == 0 || b.expired(table_name, result.Item) {
+		return ErrNotFound
+	}
+
+	err = dynamodbattribute.UnmarshalMap(unmarshalExpiry(result.Item, b.definitions.ttlAttribute(table_name)), item)
 	if err != nil {
 		return err
 	}
@@ -99,63 +193,128 @@ func (b *BackendDynamoDB) GetItemsByIds(table_name string, ids []string, sort_fi
 	return nil
 }
 
-func (b *BackendDynamoDB) GetItemByAttributeValue(table_name string, attribute_name string, attribute_value string, item interface{}) error {
+// expired returns true if an item of a table has expired.  DynamoDB deletes expired
+// items in the background, so it can return them until then.
+func (b *BackendDynamoDB) expired(table_name string, item map[string]*dynamodb.AttributeValue) bool {
+	return expiredItem(item, b.definitions.ttlAttribute(table_name), time.Now())
+}
 
-	ean := map[string]*string{}
-	ean["#a"] = aws.String(attribute_name)
+// defineTimeToLive reads the time to live attribute of a table that is not defined, so
+// that expired items are hidden without DefineTables.  If the table has none, or if it
+// cannot be described, for example without dynamodb:DescribeTimeToLive, the table is
+// read as having none until it is read again after ttlLookupInterval.
+func (b *BackendDynamoDB) defineTimeToLive(ctx context.Context, table_name string) {
+	now := time.Now()
+	if !b.definitions.lookupTTLAttribute(table_name, now) {
+		return
+	}
+	ttl_attribute, err := b.describeTimeToLive(ctx, table_name)
+	if err != nil || len(ttl_attribute) == 0 {
+		// A canceled read is not a miss.
+		if ctx.Err() == nil {
+			b.definitions.missTTLAttribute(table_name, now)
+		}
+		return
+	}
+	b.definitions.defineTTLAttribute(table_name, ttl_attribute)
+}
 
-	eav := map[string]*dynamodb.AttributeValue{}
-	eav[":v"] = &dynamodb.AttributeValue{
-		S: aws.String(attribute_value),
+// unexpired returns the items of a table that have not expired.
+func (b *BackendDynamoDB) unexpired(table_name string, items []map[string]*dynamodb.AttributeValue) []map[string]*dynamodb.AttributeValue {
+	return unexpiredItems(items, b.definitions.ttlAttribute(table_name))
+}
+
+// unsortedDynamoDB returns ErrNotSupported if there are sort fields, for the reads that
+// return items in the order that DynamoDB reads them.
+func unsortedDynamoDB(sort_fields []string) error {
+	if len(sort_fields) > 0 {
+		return &Error{Kind: ErrNotSupported, Err: fmt.Errorf("cannot sort by %s, since DynamoDB reads pages in the order of the key", strings.Join(sort_fields, ", "))}
 	}
+	return nil
+}
 
-	input := &dynamodb.QueryInput{
-		TableName:                 aws.String(table_name),
-		IndexName:                 aws.String(attribute_name + "-index"),
-		KeyConditionExpression:    aws.String("#a = :v"),
-		ExpressionAttributeNames:  ean,
-		ExpressionAttributeValues: eav,
+// key validates a key against the key schema of a table and marshals it.
+func (b *BackendDynamoDB) key(ctx context.Context, table_name string, key Key) (map[string]*dynamodb.AttributeValue, error) {
+	keys, err := b.describeKeys(ctx, table_name)
+	if err != nil {
+		return nil, err
+	}
+	err = validateKey(table_name, key, keys.HashKey, keys.RangeKey)
+	if err != nil {
+		return nil, err
 	}
+	return keyAttributeValues(key)
+}
+
+func (b *BackendDynamoDB) QueryItems(table_name string, query *KeyQuery, items interface{}) error {
+	return b.QueryItemsContext(context.Background(), table_name, query, items)
+}
 
-	result, err := b.dynamodb_client.Query(input)
+// QueryItemsContext reads the items of a partition in the order of the sort key, with
+// a key condition on the sort key.
+func (b *BackendDynamoDB) QueryItemsContext(ctx context.Context, table_name string, query *KeyQuery, items interface{}) error {
+	b.defineTimeToLive(ctx, table_name)
+
+	keys, err := b.describeKeys(ctx, table_name)
 	if err != nil {
 		return err
 	}
 
-	if len(result.Items) > 0 {
-		err = dynamodbattribute.UnmarshalMap(result.Items[0], item)
-		if err != nil {
-			return err
+	if query != nil && len(query.Index) > 0 {
+		index_keys, ok := keys.Indexes[query.Index]
+		if !ok {
+			return &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("table %q has no index %q", table_name, query.Index)}
 		}
+		keys = index_keys
 	}
 
-	return nil
-}
-
-func (b *BackendDynamoDB) GetItemsByAttributeValue(table_name string, attribute_name string, attribute_value string, sort_fields []string, items interface{}) error {
-
-	ean := map[string]*string{}
-	ean["#a"] = aws.String(attribute_name)
+	err = validateKeyQuery(table_name, query, keys.RangeKey)
+	if err != nil {
+		return err
+	}
 
-	eav := map[string]*dynamodb.AttributeValue{}
-	eav[":v"] = &dynamodb.AttributeValue{
-		S: aws.String(attribute_value),
+	e := newExpressionDynamoDB()
+	v, err := e.value(query.Partition)
+	if err != nil {
+		return err
+	}
+	key_condition := e.name(keys.HashKey) + " = " + v
+	if query.Sort != nil {
+		sort_condition, err := e.filter(query.Sort)
+		if err != nil {
+			return err
+		}
+		key_condition += " AND " + sort_condition
 	}
 
 	input := &dynamodb.QueryInput{
 		TableName:                 aws.String(table_name),
-		IndexName:                 aws.String(attribute_name + "-index"),
-		KeyConditionExpression:    aws.String("#a = :v"),
-		ExpressionAttributeNames:  ean,
-		ExpressionAttributeValues: eav,
+		KeyConditionExpression:    aws.String(key_condition),
+		ExpressionAttributeNames:  e.expressionAttributeNames(),
+		ExpressionAttributeValues: e.expressionAttributeValues(),
+		ScanIndexForward:          aws.Bool(!query.Descending),
+	}
+	if len(query.Index) > 0 {
+		input.IndexName = aws.String(query.Index)
+	}
+	if query.Limit > 0 {
+		input.Limit = aws.Int64(int64(query.Limit))
 	}
 
-	result, err := b.dynamodb_client.Query(input)
+	results := make([]map[string]*dynamodb.AttributeValue, 0)
+	err = b.dynamodb_client.QueryPagesWithContext(ctx, input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		results = append(results, b.unexpired(table_name, page.Items)...)
+		return query.Limit == 0 || len(results) < query.Limit
+	})
 	if err != nil {
-		return err
+		return wrapDynamoDBError(err)
+	}
+
+	if query.Limit > 0 && len(results) > query.Limit {
+		results = results[:query.Limit]
 	}
 
-	err = dynamodbattribute.UnmarshalListOfMaps(result.Items, items)
+	err = dynamodbattribute.UnmarshalListOfMaps(results, items)
 	if err != nil {
 		return err
 	}
@@ -163,38 +322,120 @@ func (b *BackendDynamoDB) GetItemsByAttributeValue(table_name string, attribute_
 	return nil
 }
 
-func (b *BackendDynamoDB) GetItems(table_name string, index_name string, sort_fields []string, items interface{}) error {
-	input := &dynamodb.ScanInput{
-		TableName: aws.String(table_name),
+func (b *BackendDynamoDB) GetItemsByIds(table_name string, ids []string, sort_fields []string, items interface{}) error {
+	return b.GetItemsByIdsContext(context.Background(), table_name, ids, sort_fields, items)
+}
+
+// GetItemsByIdsContext reads the items in chunks of 100 ids with BatchGetItem.  The
+// items are returned in the order of the ids, or sorted by sort_fields if any.  If
+// some of the items do not exist, the items found are still returned along with a
+// *MissingItemsError.
+func (b *BackendDynamoDB) GetItemsByIdsContext(ctx context.Context, table_name string, ids []string, sort_fields []string, items interface{}) error {
+	if err := b.requireIdKey(ctx, table_name); err != nil {
+		return err
 	}
-	if len(index_name) > 0 {
-		input.IndexName = aws.String(index_name)
+	b.defineTimeToLive(ctx, table_name)
+
+	unique := uniqueIds(ids)
+	found := map[string]map[string]*dynamodb.AttributeValue{}
+
+	for start := 0; start < len(unique); start += batchGetItemLimitDynamoDB {
+		end := start + batchGetItemLimitDynamoDB
+		if end > len(unique) {
+			end = len(unique)
+		}
+
+		keys := make([]map[string]*dynamodb.AttributeValue, 0, end-start)
+		for _, id := range unique[start:end] {
+			keys = append(keys, map[string]*dynamodb.AttributeValue{"id": &dynamodb.AttributeValue{S: aws.String(id)}})
+		}
+
+		request_items := map[string]*dynamodb.KeysAndAttributes{
+			table_name: &dynamodb.KeysAndAttributes{Keys: keys},
+		}
+
+		for attempt := 0; len(request_items) > 0; attempt++ {
+			if attempt > 0 {
+				if attempt > maxRetries {
+					return &Error{Kind: ErrThrottled, Err: fmt.Errorf("%d keys remain unprocessed after %d retries", len(request_items[table_name].Keys), maxRetries)}
+				}
+				err := sleepContext(ctx, backoff(attempt-1))
+				if err != nil {
+					return err
+				}
+			}
+			result, err := b.dynamodb_client.BatchGetItemWithContext(ctx, &dynamodb.BatchGetItemInput{
+				RequestItems: request_items,
+			})
+			if err != nil {
+				return wrapDynamoDBError(err)
+			}
+			for _, item := range b.unexpired(table_name, result.Responses[table_name]) {
+				if av, ok := item["id"]; ok && av.S != nil {
+					found[*av.S] = item
+				}
+			}
+			request_items = result.UnprocessedKeys
+		}
 	}
 
-	result, err := b.dynamodb_client.Scan(input)
+	results, missing := orderItemsByIds(unique, found, sort_fields)
+
+	err := dynamodbattribute.UnmarshalListOfMaps(results, items)
 	if err != nil {
 		return err
 	}
 
-	err = dynamodbattribute.UnmarshalListOfMaps(result.Items, items)
+	return missingItems(missing)
+}
+
+func (b *BackendDynamoDB) GetItemByAttributeValue(table_name string, attribute_name string, attribute_value string, item interface{}) error {
+	return b.GetItemByAttributeValueContext(context.Background(), table_name, attribute_name, attribute_value, item)
+}
+
+func (b *BackendDynamoDB) GetItemByAttributeValueContext(ctx context.Context, table_name string, attribute_name string, attribute_value string, item interface{}) error {
+	return b.GetItemByAttributeContext(ctx, table_name, attribute_name, attribute_value, item)
+}
+
+func (b *BackendDynamoDB) GetItemByAttribute(table_name string, attribute_name string, value interface{}, item interface{}) error {
+	return b.GetItemByAttributeContext(context.Background(), table_name, attribute_name, value, item)
+}
+
+// GetItemByAttributeContext reads the first item with the value in the index of the
+// attribute.  The value is marshaled, so indexes of numbers take numbers.
+func (b *BackendDynamoDB) GetItemByAttributeContext(ctx context.Context, table_name string, attribute_name string, value interface{}, item interface{}) error {
+	b.defineTimeToLive(ctx, table_name)
+
+	ean := map[string]*string{}
+	ean["#a"] = aws.String(attribute_name)
+
+	av, err := dynamodbattribute.Marshal(value)
 	if err != nil {
-		return err
+		return &Error{Kind: ErrInvalidQuery, Err: err}
 	}
 
-	return nil
-}
+	eav := map[string]*dynamodb.AttributeValue{}
+	eav[":v"] = av
 
-func (b *BackendDynamoDB) RemoveItemById(table_name string, id string) error {
-	input := &dynamodb.DeleteItemInput{
-		TableName: aws.String(table_name),
-		Key: map[string]*dynamodb.AttributeValue{
-			"id": {
-				S: aws.String(id),
-			},
-		},
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(table_name),
+		IndexName:                 aws.String(attribute_name + "-index"),
+		KeyConditionExpression:    aws.String("#a = :v"),
+		ExpressionAttributeNames:  ean,
+		ExpressionAttributeValues: eav,
+	}
+
+	result, err := b.dynamodb_client.QueryWithContext(ctx, input)
+	if err != nil {
+		return wrapDynamoDBError(err)
+	}
+
+	results := b.unexpired(table_name, result.Items)
+	if len(results) == 0 {
+		return ErrNotFound
 	}
 
-	_, err := b.dynamodb_client.DeleteItem(input)
+	err = dynamodbattribute.UnmarshalMap(results[0], item)
 	if err != nil {
 		return err
 	}
@@ -202,19 +443,54 @@ func (b *BackendDynamoDB) RemoveItemById(table_name string, id string) error {
 	return nil
 }
 
-func (b *BackendDynamoDB) RemoveItemByAttributeValue(table_name string, attribute_name string, attribute_value string) error {
+func (b *BackendDynamoDB) GetItemsByAttributeValue(table_name string, attribute_name string, attribute_value string, sort_fields []string, items interface{}) error {
+	return b.GetItemsByAttributeValueContext(context.Background(), table_name, attribute_name, attribute_value, sort_fields, items)
+}
 
-	key := map[string]*dynamodb.AttributeValue{}
-	key[attribute_name] = &dynamodb.AttributeValue{
-		S: aws.String(attribute_value),
+func (b *BackendDynamoDB) GetItemsByAttributeValueContext(ctx context.Context, table_name string, attribute_name string, attribute_value string, sort_fields []string, items interface{}) error {
+	return b.GetItemsByAttributeContext(ctx, table_name, attribute_name, attribute_value, sort_fields, items)
+}
+
+func (b *BackendDynamoDB) GetItemsByAttribute(table_name string, attribute_name string, value interface{}, sort_fields []string, items interface{}) error {
+	return b.GetItemsByAttributeContext(context.Background(), table_name, attribute_name, value, sort_fields, items)
+}
+
+// GetItemsByAttributeContext reads the items with the value in the index of the
+// attribute, sorted by sort_fields.
+func (b *BackendDynamoDB) GetItemsByAttributeContext(ctx context.Context, table_name string, attribute_name string, value interface{}, sort_fields []string, items interface{}) error {
+	b.defineTimeToLive(ctx, table_name)
+
+	ean := map[string]*string{}
+	ean["#a"] = aws.String(attribute_name)
+
+	av, err := dynamodbattribute.Marshal(value)
+	if err != nil {
+		return &Error{Kind: ErrInvalidQuery, Err: err}
 	}
 
-	input := &dynamodb.DeleteItemInput{
-		TableName: aws.String(table_name),
-		Key:       key,
+	eav := map[string]*dynamodb.AttributeValue{}
+	eav[":v"] = av
+
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(table_name),
+		IndexName:                 aws.String(attribute_name + "-index"),
+		KeyConditionExpression:    aws.String("#a = :v"),
+		ExpressionAttributeNames:  ean,
+		ExpressionAttributeValues: eav,
+	}
+
+	results := make([]map[string]*dynamodb.AttributeValue, 0)
+	err = b.dynamodb_client.QueryPagesWithContext(ctx, input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		results = append(results, b.unexpired(table_name, page.Items)...)
+		return true
+	})
+	if err != nil {
+		return wrapDynamoDBError(err)
 	}
 
-	_, err := b.dynamodb_client.DeleteItem(input)
+	sortAttributeValueMaps(results, sort_fields)
+
+	err = dynamodbattribute.UnmarshalListOfMaps(results, items)
 	if err != nil {
 		return err
 	}
@@ -222,7 +498,15 @@ func (b *BackendDynamoDB) RemoveItemByAttributeValue(table_name string, attribut
 	return nil
 }
 
-func (b *BackendDynamoDB) RemoveItemsByAttributeValue(table_name string, attribute_name string, attribute_value string) error {
+func (b *BackendDynamoDB) GetItemsByAttributeValuePage(table_name string, attribute_name string, attribute_value string, sort_fields []string, page_size int, token string, items interface{}) (string, error) {
+	return b.GetItemsByAttributeValuePageContext(context.Background(), table_name, attribute_name, attribute_value, sort_fields, page_size, token, items)
+}
+
+func (b *BackendDynamoDB) GetItemsByAttributeValuePageContext(ctx context.Context, table_name string, attribute_name string, attribute_value string, sort_fields []string, page_size int, token string, items interface{}) (string, error) {
+	if err := unsortedDynamoDB(sort_fields); err != nil {
+		return "", err
+	}
+	b.defineTimeToLive(ctx, table_name)
 
 	ean := map[string]*string{}
 	ean["#a"] = aws.String(attribute_name)
@@ -235,179 +519,1346 @@ func (b *BackendDynamoDB) RemoveItemsByAttributeValue(table_name string, attribu
 	input := &dynamodb.QueryInput{
 		TableName:                 aws.String(table_name),
 		IndexName:                 aws.String(attribute_name + "-index"),
+		KeyConditionExpression:    aws.String("#a = :v"),
 		ExpressionAttributeNames:  ean,
 		ExpressionAttributeValues: eav,
-		KeyConditionExpression:    aws.String("#a = :v"),
+	}
+	if page_size > 0 {
+		input.Limit = aws.Int64(int64(page_size))
+	}
+	if len(token) > 0 {
+		c := cursorDynamoDB{}
+		err := decodeCursor(token, &c)
+		if err != nil {
+			return "", err
+		}
+		input.ExclusiveStartKey = c.LastEvaluatedKey
 	}
 
-	result, err := b.dynamodb_client.Query(input)
+	result, err := b.dynamodb_client.QueryWithContext(ctx, input)
 	if err != nil {
-		return err
+		return "", wrapDynamoDBError(err)
 	}
 
-	for _, item := range result.Items {
-		b.RemoveItemById(table_name, *item["id"].S)
+	err = dynamodbattribute.UnmarshalListOfMaps(b.unexpired(table_name, result.Items), items)
+	if err != nil {
+		return "", err
 	}
 
-	return nil
+	return encodeCursorDynamoDB(result.LastEvaluatedKey)
 }
 
-func (b *BackendDynamoDB) RemoveAll(table_name string) error {
-	input := &dynamodb.QueryInput{
+func (b *BackendDynamoDB) GetItems(table_name string, index_name string, sort_fields []string, items interface{}) error {
+	return b.GetItemsContext(context.Background(), table_name, index_name, sort_fields, items)
+}
+
+func (b *BackendDynamoDB) GetItemsContext(ctx context.Context, table_name string, index_name string, sort_fields []string, items interface{}) error {
+	b.defineTimeToLive(ctx, table_name)
+
+	input := &dynamodb.ScanInput{
 		TableName: aws.String(table_name),
 	}
+	if len(index_name) > 0 {
+		input.IndexName = aws.String(index_name)
+	}
 
-	result, err := b.dynamodb_client.Query(input)
+	results := make([]map[string]*dynamodb.AttributeValue, 0)
+	err := b.dynamodb_client.ScanPagesWithContext(ctx, input, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		results = append(results, b.unexpired(table_name, page.Items)...)
+		return true
+	})
 	if err != nil {
-		return err
+		return wrapDynamoDBError(err)
 	}
 
-	for _, item := range result.Items {
-		b.RemoveItemById(table_name, *item["id"].S)
+	sortAttributeValueMaps(results, sort_fields)
+
+	err = dynamodbattribute.UnmarshalListOfMaps(results, items)
+	if err != nil {
+		return err
 	}
 
 	return nil
+}
 
+func (b *BackendDynamoDB) GetItemsPage(table_name string, index_name string, sort_fields []string, page_size int, token string, items interface{}) (string, error) {
+	return b.GetItemsPageContext(context.Background(), table_name, index_name, sort_fields, page_size, token, items)
 }
 
-func (b *BackendDynamoDB) InsertItem(table_name string, item interface{}) error {
+func (b *BackendDynamoDB) GetItemsPageContext(ctx context.Context, table_name string, index_name string, sort_fields []string, page_size int, token string, items interface{}) (string, error) {
+	if err := unsortedDynamoDB(sort_fields); err != nil {
+		return "", err
+	}
+	b.defineTimeToLive(ctx, table_name)
 
-	av, err := dynamodbattribute.MarshalMap(item)
+	input := &dynamodb.ScanInput{
+		TableName: aws.String(table_name),
+	}
+	if len(index_name) > 0 {
+		input.IndexName = aws.String(index_name)
+	}
+	if page_size > 0 {
+		input.Limit = aws.Int64(int64(page_size))
+	}
+	if len(token) > 0 {
+		c := cursorDynamoDB{}
+		err := decodeCursor(token, &c)
+		if err != nil {
+			return "", err
+		}
+		input.ExclusiveStartKey = c.LastEvaluatedKey
+	}
+
+	result, err := b.dynamodb_client.ScanWithContext(ctx, input)
 	if err != nil {
-		return errors.New("Error: Could not marshal DynamoDB item")
+		return "", wrapDynamoDBError(err)
 	}
 
-	_, err = b.dynamodb_client.PutItem(&dynamodb.PutItemInput{
-		TableName: aws.String(table_name),
-		Item:      av,
-	})
+	err = dynamodbattribute.UnmarshalListOfMaps(b.unexpired(table_name, result.Items), items)
+	if err != nil {
+		return "", err
+	}
 
-	return err
+	return encodeCursorDynamoDB(result.LastEvaluatedKey)
+}
+
+func (b *BackendDynamoDB) Scan(table_name string, index_name string, sort_fields []string) Iterator {
+	return b.ScanContext(context.Background(), table_name, index_name, sort_fields)
+}
+
+func (b *BackendDynamoDB) ScanContext(ctx context.Context, table_name string, index_name string, sort_fields []string) Iterator {
+	if err := unsortedDynamoDB(sort_fields); err != nil {
+		return &errorIterator{err: err}
+	}
+	return &iteratorDynamoDB{
+		ctx: ctx,
+		fetch: func(ctx context.Context, exclusive_start_key map[string]*dynamodb.AttributeValue) ([]map[string]*dynamodb.AttributeValue, map[string]*dynamodb.AttributeValue, error) {
+			b.defineTimeToLive(ctx, table_name)
+			input := &dynamodb.ScanInput{
+				TableName:         aws.String(table_name),
+				ExclusiveStartKey: exclusive_start_key,
+			}
+			if len(index_name) > 0 {
+				input.IndexName = aws.String(index_name)
+			}
+			result, err := b.dynamodb_client.ScanWithContext(ctx, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return b.unexpired(table_name, result.Items), result.LastEvaluatedKey, nil
+		},
+	}
+}
+
+func (b *BackendDynamoDB) Query(table_name string, attribute_name string, attribute_value string, sort_fields []string) Iterator {
+	return b.QueryContext(context.Background(), table_name, attribute_name, attribute_value, sort_fields)
+}
+
+func (b *BackendDynamoDB) QueryContext(ctx context.Context, table_name string, attribute_name string, attribute_value string, sort_fields []string) Iterator {
+	if err := unsortedDynamoDB(sort_fields); err != nil {
+		return &errorIterator{err: err}
+	}
+	return &iteratorDynamoDB{
+		ctx: ctx,
+		fetch: func(ctx context.Context, exclusive_start_key map[string]*dynamodb.AttributeValue) ([]map[string]*dynamodb.AttributeValue, map[string]*dynamodb.AttributeValue, error) {
+			b.defineTimeToLive(ctx, table_name)
+			ean := map[string]*string{}
+			ean["#a"] = aws.String(attribute_name)
+
+			eav := map[string]*dynamodb.AttributeValue{}
+			eav[":v"] = &dynamodb.AttributeValue{
+				S: aws.String(attribute_value),
+			}
+
+			input := &dynamodb.QueryInput{
+				TableName:                 aws.String(table_name),
+				IndexName:                 aws.String(attribute_name + "-index"),
+				KeyConditionExpression:    aws.String("#a = :v"),
+				ExpressionAttributeNames:  ean,
+				ExpressionAttributeValues: eav,
+				ExclusiveStartKey:         exclusive_start_key,
+			}
+			result, err := b.dynamodb_client.QueryWithContext(ctx, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return b.unexpired(table_name, result.Items), result.LastEvaluatedKey, nil
+		},
+	}
+}
+
+func (b *BackendDynamoDB) Find(table_name string, filter Filter, opts *FindOptions, items interface{}) error {
+	return b.FindContext(context.Background(), table_name, filter, opts, items)
+}
+
+func (b *BackendDynamoDB) FindContext(ctx context.Context, table_name string, filter Filter, opts *FindOptions, items interface{}) error {
+	b.defineTimeToLive(ctx, table_name)
+
+	if opts == nil {
+		opts = &FindOptions{}
+	}
+
+	query, scan, err := b.planFind(ctx, table_name, filter, opts.IndexName)
+	if err != nil {
+		return err
+	}
+
+	// Without sorting, reading can stop as soon as the limit is reached.
+	done := func(results []map[string]*dynamodb.AttributeValue) bool {
+		return opts.Limit > 0 && len(opts.SortFields) == 0 && len(results) >= opts.Limit
+	}
+
+	results := make([]map[string]*dynamodb.AttributeValue, 0)
+	if query != nil {
+		err = b.dynamodb_client.QueryPagesWithContext(ctx, query, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+			results = append(results, b.unexpired(table_name, page.Items)...)
+			return !done(results)
+		})
+	} else {
+		err = b.dynamodb_client.ScanPagesWithContext(ctx, scan, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+			results = append(results, b.unexpired(table_name, page.Items)...)
+			return !done(results)
+		})
+	}
+	if err != nil {
+		return wrapDynamoDBError(err)
+	}
+
+	sortAttributeValueMaps(results, opts.SortFields)
+	if opts.Limit > 0 && len(results) > opts.Limit {
+		results = results[:opts.Limit]
+	}
+
+	err = dynamodbattribute.UnmarshalListOfMaps(results, items)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (b *BackendDynamoDB) Count(table_name string, filter Filter) (int64, error) {
+	return b.CountContext(context.Background(), table_name, filter)
+}
+
+// CountContext counts the items that match the filter, reading every page of the query
+// or scan that Find would use without returning the items.
+func (b *BackendDynamoDB) CountContext(ctx context.Context, table_name string, filter Filter) (int64, error) {
+	b.defineTimeToLive(ctx, table_name)
+
+	if ttl_attribute := b.definitions.ttlAttribute(table_name); len(ttl_attribute) > 0 {
+		unexpired := unexpiredFilter(ttl_attribute, time.Now())
+		if filter != nil {
+			filter = And(filter, unexpired)
+		} else {
+			filter = unexpired
+		}
+	}
+
+	query, scan, err := b.planFind(ctx, table_name, filter, "")
+	if err != nil {
+		return 0, err
+	}
+
+	count := int64(0)
+	if query != nil {
+		query.Select = aws.String(dynamodb.SelectCount)
+		err = b.dynamodb_client.QueryPagesWithContext(ctx, query, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+			count += aws.Int64Value(page.Count)
+			return true
+		})
+	} else {
+		scan.Select = aws.String(dynamodb.SelectCount)
+		err = b.dynamodb_client.ScanPagesWithContext(ctx, scan, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+			count += aws.Int64Value(page.Count)
+			return true
+		})
+	}
+	if err != nil {
+		return 0, wrapDynamoDBError(err)
+	}
+
+	return count, nil
+}
+
+func (b *BackendDynamoDB) Exists(table_name string, id string) (bool, error) {
+	return b.ExistsContext(context.Background(), table_name, id)
+}
+
+// ExistsContext checks for the item with the id, only reading the id and the time to
+// live attribute.
+func (b *BackendDynamoDB) ExistsContext(ctx context.Context, table_name string, id string) (bool, error) {
+	if err := b.requireIdKey(ctx, table_name); err != nil {
+		return false, err
+	}
+	b.defineTimeToLive(ctx, table_name)
+
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String(table_name),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+		ProjectionExpression:     aws.String("#id"),
+		ExpressionAttributeNames: map[string]*string{"#id": aws.String("id")},
+	}
+	if ttl_attribute := b.definitions.ttlAttribute(table_name); len(ttl_attribute) > 0 {
+		input.ProjectionExpression = aws.String("#id, #ttl")
+		input.ExpressionAttributeNames["#ttl"] = aws.String(ttl_attribute)
+	}
+
+	result, err := b.dynamodb_client.GetItemWithContext(ctx, input)
+	if err != nil {
+		return false, wrapDynamoDBError(err)
+	}
+
+	return len(result.Item) > 0 && !b.expired(table_name, result.Item), nil
+}
+
+// planFind compiles a filter into a Query, if the filter requires the hash key of the
+// table or of one of its indexes to equal a value, or otherwise into a Scan.
+func (b *BackendDynamoDB) planFind(ctx context.Context, table_name string, filter Filter, index_name string) (*dynamodb.QueryInput, *dynamodb.ScanInput, error) {
+	keys, err := b.describeKeys(ctx, table_name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	candidates := []string{}
+	if len(index_name) > 0 {
+		if _, ok := keys.Indexes[index_name]; !ok {
+			return nil, nil, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("table %q has no index %q", table_name, index_name)}
+		}
+		candidates = append(candidates, index_name)
+	} else {
+		candidates = append(candidates, "")
+		names := make([]string, 0, len(keys.Indexes))
+		for name := range keys.Indexes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		candidates = append(candidates, names...)
+	}
+
+	parts := conjuncts(filter)
+	key := -1
+	key_index := index_name
+	for _, candidate := range candidates {
+		hash_key := keys.HashKey
+		if len(candidate) > 0 {
+			hash_key = keys.Indexes[candidate].HashKey
+		}
+		for i, part := range parts {
+			if c, ok := part.(*FilterComparison); ok && c.Operator == OperatorEq && c.Attribute == hash_key {
+				key = i
+				key_index = candidate
+				break
+			}
+		}
+		if key >= 0 {
+			break
+		}
+	}
+
+	e := newExpressionDynamoDB()
+	rest := make([]Filter, 0, len(parts))
+	for i, part := range parts {
+		if i != key {
+			rest = append(rest, part)
+		}
+	}
+	filter_expression := ""
+	if len(rest) > 0 {
+		filter_expression, err = e.filters(rest, " AND ")
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if key < 0 {
+		input := &dynamodb.ScanInput{
+			TableName: aws.String(table_name),
+		}
+		if len(index_name) > 0 {
+			input.IndexName = aws.String(index_name)
+		}
+		if len(filter_expression) > 0 {
+			input.FilterExpression = aws.String(filter_expression)
+		}
+		input.ExpressionAttributeNames = e.expressionAttributeNames()
+		input.ExpressionAttributeValues = e.expressionAttributeValues()
+		return nil, input, nil
+	}
+
+	key_condition, err := e.filter(parts[key])
+	if err != nil {
+		return nil, nil, err
+	}
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(table_name),
+		KeyConditionExpression: aws.String(key_condition),
+	}
+	if len(key_index) > 0 {
+		input.IndexName = aws.String(key_index)
+	}
+	if len(filter_expression) > 0 {
+		input.FilterExpression = aws.String(filter_expression)
+	}
+	input.ExpressionAttributeNames = e.expressionAttributeNames()
+	input.ExpressionAttributeValues = e.expressionAttributeValues()
+	return input, nil, nil
+}
+
+// describeKeys returns the key schema of a table, which is cached until the table is
+// created or deleted through this backend.
+func (b *BackendDynamoDB) describeKeys(ctx context.Context, table_name string) (*keysDynamoDB, error) {
+	b.keys_mutex.Lock()
+	keys, ok := b.keys[table_name]
+	b.keys_mutex.Unlock()
+	if ok {
+		return keys, nil
+	}
+
+	result, err := b.dynamodb_client.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(table_name),
+	})
+	if err != nil {
+		return nil, wrapDynamoDBError(err)
+	}
+
+	keys = newKeysDynamoDB(result.Table.KeySchema)
+	for _, index := range result.Table.GlobalSecondaryIndexes {
+		keys.Indexes[*index.IndexName] = newKeysDynamoDB(index.KeySchema)
+	}
+	for _, index := range result.Table.LocalSecondaryIndexes {
+		keys.Indexes[*index.IndexName] = newKeysDynamoDB(index.KeySchema)
+	}
+
+	b.keys_mutex.Lock()
+	if b.keys == nil {
+		b.keys = map[string]*keysDynamoDB{}
+	}
+	b.keys[table_name] = keys
+	b.keys_mutex.Unlock()
+
+	return keys, nil
+}
+
+// forgetKeys removes the cached key schema of a table.
+func (b *BackendDynamoDB) forgetKeys(table_name string) {
+	b.keys_mutex.Lock()
+	delete(b.keys, table_name)
+	b.keys_mutex.Unlock()
+}
+
+func newKeysDynamoDB(key_schema []*dynamodb.KeySchemaElement) *keysDynamoDB {
+	keys := &keysDynamoDB{Indexes: map[string]*keysDynamoDB{}}
+	for _, k := range key_schema {
+		switch *k.KeyType {
+		case dynamodb.KeyTypeHash:
+			keys.HashKey = *k.AttributeName
+		case dynamodb.KeyTypeRange:
+			keys.RangeKey = *k.AttributeName
+		}
+	}
+	return keys
+}
+
+func (b *BackendDynamoDB) RemoveItemById(table_name string, id string) error {
+	return b.RemoveItemByIdContext(context.Background(), table_name, id)
+}
+
+func (b *BackendDynamoDB) RemoveItemByIdContext(ctx context.Context, table_name string, id string) error {
+	if err := b.requireIdKey(ctx, table_name); err != nil {
+		return err
+	}
+
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(table_name),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	}
+
+	_, err := b.dynamodb_client.DeleteItemWithContext(ctx, input)
+	if err != nil {
+		return wrapDynamoDBError(err)
+	}
+
+	return nil
+}
+
+func (b *BackendDynamoDB) RemoveItem(table_name string, key Key) error {
+	return b.RemoveItemContext(context.Background(), table_name, key)
+}
+
+// RemoveItemContext deletes the item with the key.  Deleting a missing item is not an
+// error.
+func (b *BackendDynamoDB) RemoveItemContext(ctx context.Context, table_name string, key Key) error {
+
+	av, err := b.key(ctx, table_name, key)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.dynamodb_client.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(table_name),
+		Key:       av,
+	})
+
+	return wrapDynamoDBError(err)
+}
+
+func (b *BackendDynamoDB) RemoveItemByAttributeValue(table_name string, attribute_name string, attribute_value string) error {
+	return b.RemoveItemByAttributeValueContext(context.Background(), table_name, attribute_name, attribute_value)
+}
+
+func (b *BackendDynamoDB) RemoveItemByAttributeValueContext(ctx context.Context, table_name string, attribute_name string, attribute_value string) error {
+
+	if attribute_name == "id" {
+		return b.RemoveItemByIdContext(ctx, table_name, attribute_value)
+	}
+
+	ean := map[string]*string{}
+	ean["#a"] = aws.String(attribute_name)
+
+	eav := map[string]*dynamodb.AttributeValue{}
+	eav[":v"] = &dynamodb.AttributeValue{
+		S: aws.String(attribute_value),
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(table_name),
+		IndexName:                 aws.String(attribute_name + "-index"),
+		ExpressionAttributeNames:  ean,
+		ExpressionAttributeValues: eav,
+		KeyConditionExpression:    aws.String("#a = :v"),
+		Limit:                     aws.Int64(1),
+	}
+
+	result, err := b.dynamodb_client.QueryWithContext(ctx, input)
+	if err != nil {
+		return wrapDynamoDBError(err)
+	}
+
+	if len(result.Items) == 0 {
+		return nil
+	}
+
+	return b.removeItems(ctx, table_name, result.Items)
+}
+
+func (b *BackendDynamoDB) RemoveItemsByAttributeValue(table_name string, attribute_name string, attribute_value string) error {
+	return b.RemoveItemsByAttributeValueContext(context.Background(), table_name, attribute_name, attribute_value)
+}
+
+func (b *BackendDynamoDB) RemoveItemsByAttributeValueContext(ctx context.Context, table_name string, attribute_name string, attribute_value string) error {
+
+	ean := map[string]*string{}
+	ean["#a"] = aws.String(attribute_name)
+
+	eav := map[string]*dynamodb.AttributeValue{}
+	eav[":v"] = &dynamodb.AttributeValue{
+		S: aws.String(attribute_value),
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(table_name),
+		IndexName:                 aws.String(attribute_name + "-index"),
+		ExpressionAttributeNames:  ean,
+		ExpressionAttributeValues: eav,
+		KeyConditionExpression:    aws.String("#a = :v"),
+	}
+
+	results := make([]map[string]*dynamodb.AttributeValue, 0)
+	err := b.dynamodb_client.QueryPagesWithContext(ctx, input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		results = append(results, page.Items...)
+		return true
+	})
+	if err != nil {
+		return wrapDynamoDBError(err)
+	}
+
+	return b.removeItems(ctx, table_name, results)
+}
+
+func (b *BackendDynamoDB) RemoveAll(table_name string) error {
+	return b.RemoveAllContext(context.Background(), table_name)
+}
+
+func (b *BackendDynamoDB) RemoveAllContext(ctx context.Context, table_name string) error {
+	keys, err := b.describeKeys(ctx, table_name)
+	if err != nil {
+		return err
+	}
+
+	input := &dynamodb.ScanInput{
+		TableName:                aws.String(table_name),
+		ProjectionExpression:     aws.String("#h"),
+		ExpressionAttributeNames: map[string]*string{"#h": aws.String(keys.HashKey)},
+	}
+	if len(keys.RangeKey) > 0 {
+		input.ProjectionExpression = aws.String("#h, #r")
+		input.ExpressionAttributeNames["#r"] = aws.String(keys.RangeKey)
+	}
+
+	results := make([]map[string]*dynamodb.AttributeValue, 0)
+	err = b.dynamodb_client.ScanPagesWithContext(ctx, input, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		results = append(results, page.Items...)
+		return true
+	})
+	if err != nil {
+		return wrapDynamoDBError(err)
+	}
+
+	return b.removeItems(ctx, table_name, results)
+}
+
+// removeItems removes the items by the key attributes of the table, which every index
+// projects.  Like RemoveItem, items that are already removed are ignored, and only
+// the context can interrupt the removal.
+func (b *BackendDynamoDB) removeItems(ctx context.Context, table_name string, items []map[string]*dynamodb.AttributeValue) error {
+	keys, err := b.describeKeys(ctx, table_name)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		key, err := itemKeyDynamoDB(keys, item)
+		if err != nil {
+			return err
+		}
+		err = b.RemoveItemContext(ctx, table_name, key)
+		if err != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// itemKeyDynamoDB returns the key of an item.  Numbers are decoded as Numbers, so that
+// they are marshaled back without losing precision.
+func itemKeyDynamoDB(keys *keysDynamoDB, item map[string]*dynamodb.AttributeValue) (Key, error) {
+	decoder := dynamodbattribute.NewDecoder(func(d *dynamodbattribute.Decoder) {
+		d.UseNumber = true
+	})
+	key := Key{}
+	for _, attribute_name := range []string{keys.HashKey, keys.RangeKey} {
+		if len(attribute_name) == 0 {
+			continue
+		}
+		var v interface{}
+		err := decoder.Decode(item[attribute_name], &v)
+		if err != nil {
+			return nil, err
+		}
+		key[attribute_name] = v
+	}
+	return key, nil
+}
+
+// itemKeys returns the key schema of a table for the writes of items, which do not
+// otherwise need to describe the table.  Tables that cannot be described are assumed to
+// be keyed by id, and DynamoDB rejects the writes if they are not.
+func (b *BackendDynamoDB) itemKeys(ctx context.Context, table_name string) *keysDynamoDB {
+	keys, err := b.describeKeys(ctx, table_name)
+	if err != nil {
+		return &keysDynamoDB{HashKey: defaultPartitionKey.Name}
+	}
+	return keys
+}
+
+// requireIdKey returns ErrInvalidQuery unless the table is keyed by id.
+func (b *BackendDynamoDB) requireIdKey(ctx context.Context, table_name string) error {
+	keys := b.itemKeys(ctx, table_name)
+	return requireIdKey(table_name, keys.HashKey, keys.RangeKey)
+}
+
+// itemIdDynamoDB returns the id of an item if the table is keyed by id, or an empty
+// string otherwise, and an error if the item is missing a key attribute.
+func itemIdDynamoDB(table_name string, keys *keysDynamoDB, item map[string]*dynamodb.AttributeValue) (string, error) {
+	if keyedById(keys.HashKey, keys.RangeKey) {
+		return itemId(item)
+	}
+	for _, attribute_name := range []string{keys.HashKey, keys.RangeKey} {
+		if _, ok := item[attribute_name]; !ok && len(attribute_name) > 0 {
+			return "", &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("item is missing the key attribute %q of table %q", attribute_name, table_name)}
+		}
+	}
+	return "", nil
+}
+
+func (b *BackendDynamoDB) InsertItem(table_name string, item interface{}) error {
+	return b.InsertItemContext(context.Background(), table_name, item)
+}
+
+func (b *BackendDynamoDB) InsertItemContext(ctx context.Context, table_name string, item interface{}) error {
+	return b.putItem(ctx, table_name, item, "")
+}
+
+func (b *BackendDynamoDB) InsertItemIfNotExists(table_name string, item interface{}) error {
+	return b.InsertItemIfNotExistsContext(context.Background(), table_name, item)
+}
+
+// InsertItemIfNotExistsContext writes the item unless an item with the same id exists,
+// in which case it returns ErrConflict.
+func (b *BackendDynamoDB) InsertItemIfNotExistsContext(ctx context.Context, table_name string, item interface{}) error {
+	return b.putItem(ctx, table_name, item, "attribute_not_exists(#id)")
+}
+
+func (b *BackendDynamoDB) ReplaceItem(table_name string, item interface{}) error {
+	return b.ReplaceItemContext(context.Background(), table_name, item)
+}
+
+// ReplaceItemContext replaces an existing item, or returns ErrNotFound if no item with
+// the same id exists.  If the table is versioned, the item is only replaced if the
+// stored version equals the version of the item, and the version is incremented.
+func (b *BackendDynamoDB) ReplaceItemContext(ctx context.Context, table_name string, item interface{}) error {
+
+	version_attribute := b.definitions.versionAttribute(table_name)
+	if len(version_attribute) == 0 {
+		err := b.putItem(ctx, table_name, item, "attribute_exists(#id)")
+		if errors.Is(err, ErrConflict) {
+			return &Error{Kind: ErrNotFound, Err: errors.Unwrap(err)}
+		}
+		return err
+	}
+
+	av, err := b.marshalItem(ctx, table_name, item)
+	if err != nil {
+		return err
+	}
+
+	id, err := itemId(av)
+	if err != nil {
+		return err
+	}
+
+	version, err := versionAttributeValue(av[version_attribute])
+	if err != nil {
+		return err
+	}
+	av[version_attribute] = versionAttribute(version + 1)
+
+	e := newExpressionDynamoDB()
+	condition := "attribute_exists(" + e.name("id") + ") AND " + versionConditionDynamoDB(e, version_attribute, version)
+
+	_, err = b.dynamodb_client.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName:                 aws.String(table_name),
+		Item:                      av,
+		ConditionExpression:       aws.String(condition),
+		ExpressionAttributeNames:  e.expressionAttributeNames(),
+		ExpressionAttributeValues: e.expressionAttributeValues(),
+	})
+
+	return b.versionError(ctx, table_name, id, version, wrapDynamoDBError(err))
+}
+
+// versionConditionDynamoDB returns the condition that the version attribute equals
+// the version.  Version 0 also matches items without the attribute.
+func versionConditionDynamoDB(e *expressionDynamoDB, version_attribute string, version int64) string {
+	n := e.name(version_attribute)
+	v := e.attributeValue(versionAttribute(version))
+	if version == 0 {
+		return "(attribute_not_exists(" + n + ") OR " + n + " = " + v + ")"
+	}
+	return n + " = " + v
+}
+
+// versionError returns the error of a versioned write.  If the condition failed, it
+// returns ErrNotFound if the item does not exist, and a VersionConflictError otherwise.
+func (b *BackendDynamoDB) versionError(ctx context.Context, table_name string, id string, version int64, err error) error {
+	if !errors.Is(err, ErrConflict) {
+		return err
+	}
+	result, get_err := b.dynamodb_client.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:                aws.String(table_name),
+		Key:                      map[string]*dynamodb.AttributeValue{"id": &dynamodb.AttributeValue{S: aws.String(id)}},
+		ConsistentRead:           aws.Bool(true),
+		ProjectionExpression:     aws.String("#id"),
+		ExpressionAttributeNames: map[string]*string{"#id": aws.String("id")},
+	})
+	if get_err != nil {
+		return wrapDynamoDBError(get_err)
+	}
+	if len(result.Item) == 0 {
+		return &Error{Kind: ErrNotFound, Err: errors.Unwrap(err)}
+	}
+	return &VersionConflictError{Table: table_name, Id: id, Version: version}
+}
+
+func (b *BackendDynamoDB) UpsertItem(table_name string, item interface{}) error {
+	return b.UpsertItemContext(context.Background(), table_name, item)
+}
+
+// UpsertItemContext writes the item, replacing any item with the same id.
+func (b *BackendDynamoDB) UpsertItemContext(ctx context.Context, table_name string, item interface{}) error {
+	return b.putItem(ctx, table_name, item, "")
+}
+
+// putItem writes an item with PutItem if the condition on the #id placeholder, which is
+// the hash key of the table, holds.
+func (b *BackendDynamoDB) putItem(ctx context.Context, table_name string, item interface{}, condition string) error {
+
+	av, err := b.marshalItem(ctx, table_name, item)
+	if err != nil {
+		return err
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String(table_name),
+		Item:      av,
+	}
+
+	if len(condition) > 0 {
+		input.ConditionExpression = aws.String(condition)
+		input.ExpressionAttributeNames = map[string]*string{"#id": aws.String(b.itemKeys(ctx, table_name).HashKey)}
+	}
+
+	_, err = b.dynamodb_client.PutItemWithContext(ctx, input)
+
+	return wrapDynamoDBError(err)
+}
+
+// marshalItem marshals an item of the table, with its time to live attribute as a Unix
+// time.
+func (b *BackendDynamoDB) marshalItem(ctx context.Context, table_name string, item interface{}) (map[string]*dynamodb.AttributeValue, error) {
+	av, err := dynamodbattribute.MarshalMap(item)
+	if err != nil {
+		return nil, &Error{Kind: ErrInvalidQuery, Err: err}
+	}
+	b.defineTimeToLive(ctx, table_name)
+	marshalExpiry(av, b.definitions.ttlAttribute(table_name))
+	return av, nil
+}
+
+func (b *BackendDynamoDB) InsertItems(table_name string, items interface{}) ([]BulkResult, error) {
+	return b.InsertItemsContext(context.Background(), table_name, items)
+}
+
+// InsertItemsContext writes the items with BatchWriteItem.  BatchWriteItem does not
+// support conditions, so like InsertItem existing items are overwritten.
+func (b *BackendDynamoDB) InsertItemsContext(ctx context.Context, table_name string, items interface{}) ([]BulkResult, error) {
+	return b.PutItemsContext(ctx, table_name, items)
+}
+
+func (b *BackendDynamoDB) PutItems(table_name string, items interface{}) ([]BulkResult, error) {
+	return b.PutItemsContext(context.Background(), table_name, items)
+}
+
+func (b *BackendDynamoDB) PutItemsContext(ctx context.Context, table_name string, items interface{}) ([]BulkResult, error) {
+	values, err := sliceItems(items)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := b.itemKeys(ctx, table_name)
+	results := make([]BulkResult, len(values))
+	requests := make([]*dynamodb.WriteRequest, len(values))
+	for i, item := range values {
+		results[i].Index = i
+		av, err := b.marshalItem(ctx, table_name, item)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		// BatchWriteItem rejects every item of a request if one is missing its key.
+		id, err := itemIdDynamoDB(table_name, keys, av)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		results[i].Id = id
+		requests[i] = &dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{Item: av}}
+	}
+
+	err = b.batchWrite(ctx, table_name, requests, results)
+	if err != nil {
+		return results, err
+	}
+
+	return results, bulkError(results)
+}
+
+func (b *BackendDynamoDB) DeleteItemsByIds(table_name string, ids []string) ([]BulkResult, error) {
+	return b.DeleteItemsByIdsContext(context.Background(), table_name, ids)
+}
+
+func (b *BackendDynamoDB) DeleteItemsByIdsContext(ctx context.Context, table_name string, ids []string) ([]BulkResult, error) {
+	if err := b.requireIdKey(ctx, table_name); err != nil {
+		return nil, err
+	}
+
+	results := make([]BulkResult, len(ids))
+	requests := make([]*dynamodb.WriteRequest, len(ids))
+	for i, id := range ids {
+		results[i].Index = i
+		results[i].Id = id
+		if len(id) == 0 {
+			results[i].Err = &Error{Kind: ErrInvalidQuery, Err: errors.New("id is empty")}
+			continue
+		}
+		key := map[string]*dynamodb.AttributeValue{"id": &dynamodb.AttributeValue{S: aws.String(id)}}
+		requests[i] = &dynamodb.WriteRequest{DeleteRequest: &dynamodb.DeleteRequest{Key: key}}
+	}
+
+	err := b.batchWrite(ctx, table_name, requests, results)
+	if err != nil {
+		return results, err
+	}
+
+	return results, bulkError(results)
+}
+
+// batchWrite runs the write requests in chunks of 25 with BatchWriteItem and retries
+// the unprocessed items with backoff.  Nil requests are skipped.  The errors of the
+// requests are recorded in results, and only a done context stops the remaining
+// chunks.
+func (b *BackendDynamoDB) batchWrite(ctx context.Context, table_name string, requests []*dynamodb.WriteRequest, results []BulkResult) error {
+
+	pending := make([]int, 0, len(requests))
+	for i, r := range requests {
+		if r != nil {
+			pending = append(pending, i)
+		}
+	}
+
+	for start := 0; start < len(pending); start += batchWriteItemLimitDynamoDB {
+		end := start + batchWriteItemLimitDynamoDB
+		if end > len(pending) {
+			end = len(pending)
+		}
+
+		// The indexes of the requests by id, to match the unprocessed items.
+		indexes := map[string][]int{}
+		writes := make([]*dynamodb.WriteRequest, 0, end-start)
+		for _, i := range pending[start:end] {
+			indexes[results[i].Id] = append(indexes[results[i].Id], i)
+			writes = append(writes, requests[i])
+		}
+
+		request_items := map[string][]*dynamodb.WriteRequest{table_name: writes}
+		var err error
+		for attempt := 0; len(request_items[table_name]) > 0; attempt++ {
+			if attempt > 0 {
+				if attempt > maxRetries {
+					err = &Error{Kind: ErrThrottled, Err: fmt.Errorf("items remain unprocessed after %d retries", maxRetries)}
+					break
+				}
+				err = sleepContext(ctx, backoff(attempt-1))
+				if err != nil {
+					break
+				}
+			}
+			var result *dynamodb.BatchWriteItemOutput
+			result, err = b.dynamodb_client.BatchWriteItemWithContext(ctx, &dynamodb.BatchWriteItemInput{
+				RequestItems: request_items,
+			})
+			if err != nil {
+				err = wrapDynamoDBError(err)
+				break
+			}
+			request_items = result.UnprocessedItems
+		}
+
+		if err != nil {
+			for _, w := range request_items[table_name] {
+				for _, i := range indexes[writeRequestIdDynamoDB(w)] {
+					results[i].Err = err
+				}
+			}
+		}
+
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			for _, i := range pending[end:] {
+				results[i].Err = ctxErr
+			}
+			return ctxErr
+		}
+	}
+
+	return nil
+}
+
+// writeRequestIdDynamoDB returns the id of the item of a write request.
+func writeRequestIdDynamoDB(w *dynamodb.WriteRequest) string {
+	var key map[string]*dynamodb.AttributeValue
+	if w.PutRequest != nil {
+		key = w.PutRequest.Item
+	} else if w.DeleteRequest != nil {
+		key = w.DeleteRequest.Key
+	}
+	if av, ok := key["id"]; ok && av.S != nil {
+		return *av.S
+	}
+	return ""
 }
 
 func (b *BackendDynamoDB) UpdateItemById(table_name string, id string, values map[string]interface{}) error {
+	return b.UpdateItemByIdContext(context.Background(), table_name, id, values)
+}
 
-	valuesAsSlice := make([]struct {
-		Key   string
-		Value interface{}
-	}, 0)
-	for k, v := range values {
-		valuesAsSlice = append(valuesAsSlice, struct {
-			Key   string
-			Value interface{}
-		}{Key: k, Value: v})
+// UpdateItemByIdContext sets the attributes of an item to the values, and removes the
+// attributes with nil values.  If the table is versioned, the value of the version
+// attribute is the expected version of the item.
+func (b *BackendDynamoDB) UpdateItemByIdContext(ctx context.Context, table_name string, id string, values map[string]interface{}) error {
+	update, err := updateFromValues(values, b.definitions.versionAttribute(table_name))
+	if err != nil {
+		return err
 	}
+	return b.UpdateItemContext(ctx, table_name, id, update)
+}
 
-	ean := map[string]*string{}
-	for i, v := range valuesAsSlice {
-		var buf bytes.Buffer
-		buf.WriteRune(rune(97 + i))
-		ean["#"+buf.String()] = aws.String(v.Key)
+func (b *BackendDynamoDB) UpdateItem(table_name string, id string, update *Update) error {
+	return b.UpdateItemContext(context.Background(), table_name, id, update)
+}
+
+// UpdateItemContext applies the update to an item with UpdateItem, creating the item
+// if it does not exist.  If the table is versioned, the item must exist at the
+// version of the update, and the version is incremented.
+func (b *BackendDynamoDB) UpdateItemContext(ctx context.Context, table_name string, id string, update *Update) error {
+	if err := b.requireIdKey(ctx, table_name); err != nil {
+		return err
 	}
 
-	eav := map[string]*dynamodb.AttributeValue{}
-	for i, v := range valuesAsSlice {
-		var buf bytes.Buffer
-		buf.WriteRune(rune(97 + i))
-		an := ":" + buf.String()
-		switch v.Value.(type) {
-		case string:
-			eav[an] = &dynamodb.AttributeValue{
-				S: aws.String(v.Value.(string)),
+	version_attribute := b.definitions.versionAttribute(table_name)
+	err := update.validate(table_name, "id", version_attribute)
+	if err != nil {
+		return err
+	}
+
+	b.defineTimeToLive(ctx, table_name)
+	operations := marshalExpiryOperations(update.Operations, b.definitions.ttlAttribute(table_name))
+	e := newExpressionDynamoDB()
+	var condition *string
+	if len(version_attribute) > 0 {
+		condition = aws.String("attribute_exists(" + e.name("id") + ") AND " + versionConditionDynamoDB(e, version_attribute, *update.Version))
+		operations = append(append([]UpdateOperation{}, operations...), UpdateOperation{Operator: UpdateSet, Attribute: version_attribute, Value: *update.Version + 1})
+	}
+
+	expression, err := e.update(operations)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.dynamodb_client.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(table_name),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+		ExpressionAttributeNames:  e.expressionAttributeNames(),
+		ExpressionAttributeValues: e.expressionAttributeValues(),
+		UpdateExpression:          aws.String(expression),
+		ConditionExpression:       condition,
+	})
+
+	if len(version_attribute) > 0 {
+		return b.versionError(ctx, table_name, id, *update.Version, wrapDynamoDBError(err))
+	}
+
+	return wrapDynamoDBError(err)
+}
+
+func (b *BackendDynamoDB) WriteTransaction(tx *Transaction) error {
+	return b.WriteTransactionContext(context.Background(), tx)
+}
+
+// WriteTransactionContext writes the items with TransactWriteItems.  The current items
+// of failed conditions are returned with the cancellation reasons, so that version
+// conflicts can be told apart from other failed conditions without reading the items.
+func (b *BackendDynamoDB) WriteTransactionContext(ctx context.Context, tx *Transaction) error {
+
+	ids, items, err := tx.items()
+	if err != nil {
+		return err
+	}
+	for i, op := range tx.Operations {
+		if err := b.requireIdKey(ctx, op.Table); err != nil {
+			return err
+		}
+		b.defineTimeToLive(ctx, op.Table)
+		if op.Operator == TransactionPut {
+			marshalExpiry(items[i], b.definitions.ttlAttribute(op.Table))
+		}
+	}
+
+	versions := make([]*int64, len(tx.Operations))
+	transact_items := make([]*dynamodb.TransactWriteItem, 0, len(tx.Operations))
+	for i, op := range tx.Operations {
+		e := newExpressionDynamoDB()
+		conditions := []string{}
+		if op.Condition != nil {
+			condition, err := e.filter(op.Condition)
+			if err != nil {
+				return err
+			}
+			conditions = append(conditions, condition)
+		}
+		var update_expression *string
+		if op.Operator == TransactionUpdate {
+			version_attribute := b.definitions.versionAttribute(op.Table)
+			err := op.Update.validate(op.Table, "id", version_attribute)
+			if err != nil {
+				return err
+			}
+			operations := marshalExpiryOperations(op.Update.Operations, b.definitions.ttlAttribute(op.Table))
+			if len(version_attribute) > 0 {
+				versions[i] = op.Update.Version
+				conditions = append(conditions, "attribute_exists("+e.name("id")+")", versionConditionDynamoDB(e, version_attribute, *op.Update.Version))
+				operations = append(append([]UpdateOperation{}, operations...), UpdateOperation{Operator: UpdateSet, Attribute: version_attribute, Value: *op.Update.Version + 1})
+			}
+			expression, err := e.update(operations)
+			if err != nil {
+				return err
 			}
-		default:
-			log.Println("Error: Could not update item.")
+			update_expression = aws.String(expression)
+		}
+		var condition *string
+		if len(conditions) > 0 {
+			condition = aws.String(strings.Join(conditions, " AND "))
+		}
+		key := map[string]*dynamodb.AttributeValue{"id": {S: aws.String(ids[i])}}
+		names := e.expressionAttributeNames()
+		values := e.expressionAttributeValues()
+		on_failure := aws.String(dynamodb.ReturnValuesOnConditionCheckFailureAllOld)
+		switch op.Operator {
+		case TransactionPut:
+			transact_items = append(transact_items, &dynamodb.TransactWriteItem{Put: &dynamodb.Put{
+				TableName:                           aws.String(op.Table),
+				Item:                                items[i],
+				ConditionExpression:                 condition,
+				ExpressionAttributeNames:            names,
+				ExpressionAttributeValues:           values,
+				ReturnValuesOnConditionCheckFailure: on_failure,
+			}})
+		case TransactionUpdate:
+			transact_items = append(transact_items, &dynamodb.TransactWriteItem{Update: &dynamodb.Update{
+				TableName:                           aws.String(op.Table),
+				Key:                                 key,
+				UpdateExpression:                    update_expression,
+				ConditionExpression:                 condition,
+				ExpressionAttributeNames:            names,
+				ExpressionAttributeValues:           values,
+				ReturnValuesOnConditionCheckFailure: on_failure,
+			}})
+		case TransactionDelete:
+			transact_items = append(transact_items, &dynamodb.TransactWriteItem{Delete: &dynamodb.Delete{
+				TableName:                           aws.String(op.Table),
+				Key:                                 key,
+				ConditionExpression:                 condition,
+				ExpressionAttributeNames:            names,
+				ExpressionAttributeValues:           values,
+				ReturnValuesOnConditionCheckFailure: on_failure,
+			}})
+		case TransactionConditionCheck:
+			transact_items = append(transact_items, &dynamodb.TransactWriteItem{ConditionCheck: &dynamodb.ConditionCheck{
+				TableName:                           aws.String(op.Table),
+				Key:                                 key,
+				ConditionExpression:                 condition,
+				ExpressionAttributeNames:            names,
+				ExpressionAttributeValues:           values,
+				ReturnValuesOnConditionCheckFailure: on_failure,
+			}})
 		}
 	}
 
-	attributesToSet := []string{}
-	attributesToRemove := []string{}
+	_, err = b.dynamodb_client.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: transact_items,
+	})
 
-	for i, v := range valuesAsSlice {
-		var buf bytes.Buffer
-		buf.WriteRune(rune(97 + i))
-		an := buf.String()
-		switch v.Value.(type) {
-		case string:
-			if len(v.Value.(string)) > 0 {
-				attributesToSet = append(attributesToSet, an)
-			} else {
-				attributesToRemove = append(attributesToRemove, an)
-			}
-		default:
-			log.Println("Error: Could not update item.")
+	canceled, ok := err.(*dynamodb.TransactionCanceledException)
+	if !ok {
+		return wrapDynamoDBError(err)
+	}
+	results := make([]BulkResult, len(tx.Operations))
+	for i, op := range tx.Operations {
+		results[i].Index = i
+		results[i].Id = ids[i]
+		if i < len(canceled.CancellationReasons) {
+			results[i].Err = cancellationReasonError(canceled.CancellationReasons[i], op.Table, ids[i], b.definitions.versionAttribute(op.Table), versions[i])
 		}
 	}
-	updateExpression := ""
-	if len(attributesToSet) > 0 {
-		parts := []string{}
-		for _, a := range attributesToSet {
-			parts = append(parts, "#"+a+" = :"+a)
+	err = transactionError(results)
+	if err == nil {
+		return &Error{Kind: ErrConflict, Err: canceled}
+	}
+	return err
+}
+
+// cancellationReasonError returns the error of an operation of a canceled
+// transaction, or nil if the operation did not fail.  If the operation updates a
+// versioned item, a failed condition is reported as missing item or as a version
+// conflict when the version of the current item differs.
+func cancellationReasonError(reason *dynamodb.CancellationReason, table_name string, id string, version_attribute string, version *int64) error {
+	code := aws.StringValue(reason.Code)
+	err := errors.New(code + ": " + aws.StringValue(reason.Message))
+	switch code {
+	case "", "None":
+		return nil
+	case "ConditionalCheckFailed":
+		if version != nil {
+			if reason.Item == nil {
+				return &Error{Kind: ErrNotFound, Err: err}
+			}
+			stored, version_err := versionAttributeValue(reason.Item[version_attribute])
+			if version_err != nil || stored != *version {
+				return &VersionConflictError{Table: table_name, Id: id, Version: *version}
+			}
 		}
-		updateExpression = updateExpression + "SET " + strings.Join(parts, ", ")
+		return &Error{Kind: ErrConflict, Err: err}
+	case "TransactionConflict":
+		return &Error{Kind: ErrConflict, Err: err}
+	case "ThrottlingError", "ProvisionedThroughputExceeded", "RequestLimitExceeded":
+		return &Error{Kind: ErrThrottled, Err: err}
+	case "ValidationError":
+		return &Error{Kind: ErrInvalidQuery, Err: err}
+	}
+	return err
+}
+
+func (b *BackendDynamoDB) ReadTransaction(reads []TransactionRead) error {
+	return b.ReadTransactionContext(context.Background(), reads)
+}
+
+// ReadTransactionContext reads the items with TransactGetItems.  If some of the items
+// do not exist, the items found are still read and a MissingItemsError is returned.
+func (b *BackendDynamoDB) ReadTransactionContext(ctx context.Context, reads []TransactionRead) error {
+
+	err := validateReads(reads)
+	if err != nil {
+		return err
 	}
-	if len(attributesToRemove) > 0 {
-		if len(updateExpression) > 0 {
-			updateExpression = updateExpression + " "
+	for _, r := range reads {
+		if err := b.requireIdKey(ctx, r.Table); err != nil {
+			return err
 		}
-		updateExpression = updateExpression + "REMOVE " + strings.Join(attributesToRemove, ", ")
+		b.defineTimeToLive(ctx, r.Table)
 	}
 
-	_, err := b.dynamodb_client.UpdateItem(&dynamodb.UpdateItemInput{
-		TableName: aws.String(table_name),
-		Key: map[string]*dynamodb.AttributeValue{
-			"id": {
-				S: aws.String(id),
+	transact_items := make([]*dynamodb.TransactGetItem, 0, len(reads))
+	for _, r := range reads {
+		transact_items = append(transact_items, &dynamodb.TransactGetItem{
+			Get: &dynamodb.Get{
+				TableName: aws.String(r.Table),
+				Key:       map[string]*dynamodb.AttributeValue{"id": {S: aws.String(r.Id)}},
 			},
-		},
-		ExpressionAttributeNames:  ean,
-		ExpressionAttributeValues: eav,
-		UpdateExpression:          aws.String(updateExpression),
+		})
+	}
+
+	output, err := b.dynamodb_client.TransactGetItemsWithContext(ctx, &dynamodb.TransactGetItemsInput{
+		TransactItems: transact_items,
 	})
+	if err != nil {
+		if _, ok := err.(*dynamodb.TransactionCanceledException); ok {
+			return &Error{Kind: ErrConflict, Err: err}
+		}
+		return wrapDynamoDBError(err)
+	}
 
-	return err
+	missing := make([]string, 0)
+	for i, r := range reads {
+		if i >= len(output.Responses) || len(output.Responses[i].Item) == 0 || b.expired(r.Table, output.Responses[i].Item) {
+			missing = append(missing, r.Id)
+			continue
+		}
+		err = dynamodbattribute.UnmarshalMap(unmarshalExpiry(output.Responses[i].Item, b.definitions.ttlAttribute(r.Table)), r.Item)
+		if err != nil {
+			return err
+		}
+	}
+
+	return missingItems(missing)
+}
+
+func (b *BackendDynamoDB) DefineTables(tables []Table) error {
+	b.definitions.define(tables)
+	return nil
 }
 
 func (b *BackendDynamoDB) CreateTables(tables []Table) error {
+	return b.CreateTablesContext(context.Background(), tables)
+}
+
+func (b *BackendDynamoDB) CreateTablesContext(ctx context.Context, tables []Table) error {
+	b.definitions.define(tables)
 	var err error
 	for _, t := range tables {
-		err = b.CreateTable(t.Name, t.Indexes, t.ReadUnits, t.WriteUnits)
+		err = b.CreateTableContext(ctx, t.Name, t.Indexes, t.ReadUnits, t.WriteUnits)
+		if err != nil {
+			break
+		}
+		if b.wait_for_active {
+			// CreateTable has waited until the table is active.
+			continue
+		}
+		err = sleepContext(ctx, 1000*time.Millisecond)
 		if err != nil {
 			break
 		}
-		time.Sleep(1000 * time.Millisecond)
 	}
 	return err
 }
 
 func (b *BackendDynamoDB) CreateTable(table_name string, indexes []string, readUnits int, writeUnits int) error {
+	return b.CreateTableContext(context.Background(), table_name, indexes, readUnits, writeUnits)
+}
+
+func (b *BackendDynamoDB) CreateTableContext(ctx context.Context, table_name string, indexes []string, readUnits int, writeUnits int) error {
+
+	throughput := b.definitions.throughput(table_name, readUnits, writeUnits)
+	err := throughput.validate(table_name)
+	if err != nil {
+		return err
+	}
 
-	pt := &dynamodb.ProvisionedThroughput{
-		ReadCapacityUnits:  aws.Int64(int64(readUnits)),
-		WriteCapacityUnits: aws.Int64(int64(writeUnits)),
+	partition_key, sort_key := b.definitions.keySchema(table_name)
+
+	table_indexes, err := b.definitions.indexes(table_name, indexes)
+	if err != nil {
+		return err
 	}
 
-	ad := []*dynamodb.AttributeDefinition{
-		&dynamodb.AttributeDefinition{AttributeName: aws.String("id"), AttributeType: aws.String("S")},
+	ad := []*dynamodb.AttributeDefinition{}
+	defined := map[string]bool{}
+	// DynamoDB rejects duplicate attribute definitions.
+	define := func(attribute_name string) {
+		if len(attribute_name) > 0 && !defined[attribute_name] {
+			defined[attribute_name] = true
+			ad = append(ad, &dynamodb.AttributeDefinition{
+				AttributeName: aws.String(attribute_name),
+				AttributeType: aws.String(string(b.definitions.attributeType(table_name, attribute_name))),
+			})
+		}
 	}
 
+	define(partition_key.Name)
+	define(sort_key.Name)
+	ks := keySchemaDynamoDB(partition_key.Name, sort_key.Name)
+
 	gsi := []*dynamodb.GlobalSecondaryIndex{}
-	if len(indexes) > 0 {
-		for _, index := range indexes {
-			ad = append(ad, &dynamodb.AttributeDefinition{
-				AttributeName: aws.String(index),
-				AttributeType: aws.String("S"),
+	lsi := []*dynamodb.LocalSecondaryIndex{}
+	for _, index := range table_indexes {
+		index = throughput.index(index)
+		define(index.HashKey)
+		define(index.RangeKey)
+		projection := projectionDynamoDB(index)
+		if index.Local {
+			lsi = append(lsi, &dynamodb.LocalSecondaryIndex{
+				IndexName:  aws.String(index.Name),
+				KeySchema:  keySchemaDynamoDB(index.HashKey, index.RangeKey),
+				Projection: projection,
 			})
+		} else {
 			gsi = append(gsi, &dynamodb.GlobalSecondaryIndex{
-				IndexName: aws.String(index + "-index"),
-				KeySchema: []*dynamodb.KeySchemaElement{
-					&dynamodb.KeySchemaElement{AttributeName: aws.String(index), KeyType: aws.String("HASH")},
-				},
-				Projection: &dynamodb.Projection{
-					NonKeyAttributes: nil,
-					ProjectionType:   aws.String("ALL"),
-				},
-				ProvisionedThroughput: pt,
+				IndexName:             aws.String(index.Name),
+				KeySchema:             keySchemaDynamoDB(index.HashKey, index.RangeKey),
+				Projection:            projection,
+				ProvisionedThroughput: provisionedThroughputDynamoDB(index.ReadUnits, index.WriteUnits),
 			})
 		}
 	}
@@ -415,45 +1866,330 @@ func (b *BackendDynamoDB) CreateTable(table_name string, indexes []string, readU
 	input := &dynamodb.CreateTableInput{
 		TableName:            aws.String(table_name),
 		AttributeDefinitions: ad,
-		KeySchema: []*dynamodb.KeySchemaElement{
-			&dynamodb.KeySchemaElement{AttributeName: aws.String("id"), KeyType: aws.String("HASH")},
-		},
-		ProvisionedThroughput: pt,
+		KeySchema:            ks,
+		BillingMode:          aws.String(string(throughput.billingMode())),
+	}
+	if throughput.billingMode() == BillingModeProvisioned {
+		input.ProvisionedThroughput = provisionedThroughputDynamoDB(readUnits, writeUnits)
 	}
 	if len(gsi) > 0 {
 		input.SetGlobalSecondaryIndexes(gsi)
 	}
+	if len(lsi) > 0 {
+		input.SetLocalSecondaryIndexes(lsi)
+	}
+
+	b.forgetKeys(table_name)
+	_, err = b.dynamodb_client.CreateTableWithContext(ctx, input)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeResourceInUseException {
+			return &Error{Kind: ErrTableExists, Err: err}
+		}
+		return wrapDynamoDBError(err)
+	}
+
+	// DynamoDB only enables time to live on active tables.
+	ttl_attribute := b.definitions.ttlAttribute(table_name)
+	if b.wait_for_active || len(ttl_attribute) > 0 {
+		err = b.waitForTable(ctx, table_name)
+		if err != nil {
+			return err
+		}
+	}
+	if len(ttl_attribute) > 0 {
+		return b.UpdateTimeToLiveContext(ctx, table_name, ttl_attribute)
+	}
+
+	return nil
+
+}
+
+func (b *BackendDynamoDB) UpdateThroughput(table_name string, throughput *Throughput) error {
+	return b.UpdateThroughputContext(context.Background(), table_name, throughput)
+}
+
+// UpdateThroughputContext changes the billing mode of a table and the units of the
+// table and of its global indexes, and waits until the table is active.  Only the
+// units that differ are updated, since DynamoDB rejects updates that change nothing.
+func (b *BackendDynamoDB) UpdateThroughputContext(ctx context.Context, table_name string, throughput *Throughput) error {
+	err := throughput.validate(table_name)
+	if err != nil {
+		return err
+	}
 
-	_, err := b.dynamodb_client.CreateTable(input)
+	description, err := b.DescribeTableContext(ctx, table_name)
 	if err != nil {
 		return err
 	}
 
+	mode := throughput.billingMode()
+	input := &dynamodb.UpdateTableInput{
+		TableName: aws.String(table_name),
+	}
+	changed := false
+	if description.BillingMode != mode {
+		input.BillingMode = aws.String(string(mode))
+		changed = true
+	}
+	if mode == BillingModeProvisioned {
+		if description.BillingMode != mode || description.ReadUnits != throughput.ReadUnits || description.WriteUnits != throughput.WriteUnits {
+			input.ProvisionedThroughput = provisionedThroughputDynamoDB(throughput.ReadUnits, throughput.WriteUnits)
+			changed = true
+		}
+		updates := []*dynamodb.GlobalSecondaryIndexUpdate{}
+		for _, current := range description.SecondaryIndexes {
+			if current.Local {
+				continue
+			}
+			index := throughput.index(current)
+			if description.BillingMode != mode || index.ReadUnits != current.ReadUnits || index.WriteUnits != current.WriteUnits {
+				updates = append(updates, &dynamodb.GlobalSecondaryIndexUpdate{
+					Update: &dynamodb.UpdateGlobalSecondaryIndexAction{
+						IndexName:             aws.String(index.Name),
+						ProvisionedThroughput: provisionedThroughputDynamoDB(index.ReadUnits, index.WriteUnits),
+					},
+				})
+			}
+		}
+		if len(updates) > 0 {
+			input.GlobalSecondaryIndexUpdates = updates
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	_, err = b.dynamodb_client.UpdateTableWithContext(ctx, input)
+	if err != nil {
+		return wrapDynamoDBError(err)
+	}
+	return b.waitForTable(ctx, table_name)
+}
+
+func (b *BackendDynamoDB) UpdateTimeToLive(table_name string, ttl_attribute string) error {
+	return b.UpdateTimeToLiveContext(context.Background(), table_name, ttl_attribute)
+}
+
+// UpdateTimeToLiveContext enables time to live on the attribute, or disables it if the
+// attribute is empty, and defines the attribute for the table so that expired items are
+// not returned.  DynamoDB only allows one attribute, so the current one is disabled
+// first.
+func (b *BackendDynamoDB) UpdateTimeToLiveContext(ctx context.Context, table_name string, ttl_attribute string) error {
+	current, err := b.describeTimeToLive(ctx, table_name)
+	if err != nil {
+		return err
+	}
+	if current == ttl_attribute {
+		b.definitions.defineTTLAttribute(table_name, ttl_attribute)
+		return nil
+	}
+	if len(current) > 0 {
+		_, err := b.dynamodb_client.UpdateTimeToLiveWithContext(ctx, &dynamodb.UpdateTimeToLiveInput{
+			TableName: aws.String(table_name),
+			TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
+				AttributeName: aws.String(current),
+				Enabled:       aws.Bool(false),
+			},
+		})
+		if err != nil {
+			return wrapDynamoDBError(err)
+		}
+		b.definitions.defineTTLAttribute(table_name, "")
+	}
+	if len(ttl_attribute) > 0 {
+		_, err := b.dynamodb_client.UpdateTimeToLiveWithContext(ctx, &dynamodb.UpdateTimeToLiveInput{
+			TableName: aws.String(table_name),
+			TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
+				AttributeName: aws.String(ttl_attribute),
+				Enabled:       aws.Bool(true),
+			},
+		})
+		if err != nil {
+			return wrapDynamoDBError(err)
+		}
+		b.definitions.defineTTLAttribute(table_name, ttl_attribute)
+	}
 	return nil
+}
+
+// describeTimeToLive returns the time to live attribute of a table, or an empty string
+// if time to live is disabled.
+func (b *BackendDynamoDB) describeTimeToLive(ctx context.Context, table_name string) (string, error) {
+	result, err := b.dynamodb_client.DescribeTimeToLiveWithContext(ctx, &dynamodb.DescribeTimeToLiveInput{
+		TableName: aws.String(table_name),
+	})
+	if err != nil {
+		return "", wrapDynamoDBError(err)
+	}
+	ttl := result.TimeToLiveDescription
+	if ttl == nil {
+		return "", nil
+	}
+	switch aws.StringValue(ttl.TimeToLiveStatus) {
+	case dynamodb.TimeToLiveStatusEnabled, dynamodb.TimeToLiveStatusEnabling:
+		return aws.StringValue(ttl.AttributeName), nil
+	}
+	return "", nil
+}
+
+// keySchemaDynamoDB returns the key schema of a table or index, without a range key if
+// its name is empty.
+func keySchemaDynamoDB(hash_key string, range_key string) []*dynamodb.KeySchemaElement {
+	ks := []*dynamodb.KeySchemaElement{
+		&dynamodb.KeySchemaElement{AttributeName: aws.String(hash_key), KeyType: aws.String("HASH")},
+	}
+	if len(range_key) > 0 {
+		ks = append(ks, &dynamodb.KeySchemaElement{AttributeName: aws.String(range_key), KeyType: aws.String("RANGE")})
+	}
+	return ks
+}
+
+func (b *BackendDynamoDB) ListTables() ([]string, error) {
+	return b.ListTablesContext(context.Background())
+}
+
+func (b *BackendDynamoDB) ListTablesContext(ctx context.Context) ([]string, error) {
+	table_names := []string{}
+	err := b.dynamodb_client.ListTablesPagesWithContext(ctx, &dynamodb.ListTablesInput{}, func(page *dynamodb.ListTablesOutput, lastPage bool) bool {
+		table_names = append(table_names, aws.StringValueSlice(page.TableNames)...)
+		return true
+	})
+	if err != nil {
+		return nil, wrapDynamoDBError(err)
+	}
+	return table_names, nil
+}
+
+func (b *BackendDynamoDB) DescribeTable(table_name string) (*TableDescription, error) {
+	return b.DescribeTableContext(context.Background(), table_name)
+}
+
+// DescribeTableContext returns the key schema, indexes, throughput, and status of a
+// table.  Tables with on-demand capacity have no throughput.
+func (b *BackendDynamoDB) DescribeTableContext(ctx context.Context, table_name string) (*TableDescription, error) {
+	result, err := b.dynamodb_client.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(table_name),
+	})
+	if err != nil {
+		return nil, wrapDynamoDBError(err)
+	}
+
+	types := map[string]AttributeType{}
+	for _, ad := range result.Table.AttributeDefinitions {
+		types[*ad.AttributeName] = AttributeType(*ad.AttributeType)
+	}
+	attribute := func(attribute_name string) Attribute {
+		if len(attribute_name) == 0 {
+			return Attribute{}
+		}
+		return Attribute{Name: attribute_name, Type: types[attribute_name]}
+	}
+	index := func(index_name *string, key_schema []*dynamodb.KeySchemaElement, projection *dynamodb.Projection, pt *dynamodb.ProvisionedThroughputDescription) Index {
+		keys := newKeysDynamoDB(key_schema)
+		index := Index{
+			Name:                 aws.StringValue(index_name),
+			HashKey:              keys.HashKey,
+			RangeKey:             keys.RangeKey,
+			Projection:           ProjectionType(aws.StringValue(projection.ProjectionType)),
+			ProjectionAttributes: aws.StringValueSlice(projection.NonKeyAttributes),
+			Local:                pt == nil,
+		}
+		if pt != nil {
+			index.ReadUnits = int(aws.Int64Value(pt.ReadCapacityUnits))
+			index.WriteUnits = int(aws.Int64Value(pt.WriteCapacityUnits))
+		}
+		return index
+	}
+
+	keys := newKeysDynamoDB(result.Table.KeySchema)
+	description := &TableDescription{
+		Table: Table{
+			Name:             table_name,
+			PartitionKey:     attribute(keys.HashKey),
+			SortKey:          attribute(keys.RangeKey),
+			SecondaryIndexes: []Index{},
+		},
+		Status:    TableStatus(aws.StringValue(result.Table.TableStatus)),
+		ItemCount: aws.Int64Value(result.Table.ItemCount),
+		SizeBytes: aws.Int64Value(result.Table.TableSizeBytes),
+	}
+	// Tables created before on-demand billing have no billing mode summary.
+	description.BillingMode = BillingModeProvisioned
+	if result.Table.BillingModeSummary != nil && result.Table.BillingModeSummary.BillingMode != nil {
+		description.BillingMode = BillingMode(*result.Table.BillingModeSummary.BillingMode)
+	}
+	if pt := result.Table.ProvisionedThroughput; pt != nil {
+		description.ReadUnits = int(aws.Int64Value(pt.ReadCapacityUnits))
+		description.WriteUnits = int(aws.Int64Value(pt.WriteCapacityUnits))
+	}
+	for _, x := range result.Table.GlobalSecondaryIndexes {
+		pt := x.ProvisionedThroughput
+		if pt == nil {
+			pt = &dynamodb.ProvisionedThroughputDescription{}
+		}
+		description.SecondaryIndexes = append(description.SecondaryIndexes, index(x.IndexName, x.KeySchema, x.Projection, pt))
+	}
+	for _, x := range result.Table.LocalSecondaryIndexes {
+		description.SecondaryIndexes = append(description.SecondaryIndexes, index(x.IndexName, x.KeySchema, x.Projection, nil))
+	}
+	description.TTLAttribute, err = b.describeTimeToLive(ctx, table_name)
+	if err != nil {
+		return nil, err
+	}
+	return description, nil
+}
+
+func (b *BackendDynamoDB) TableExists(table_name string) (bool, error) {
+	return b.TableExistsContext(context.Background(), table_name)
+}
+
+func (b *BackendDynamoDB) TableExistsContext(ctx context.Context, table_name string) (bool, error) {
+	_, err := b.DescribeTableContext(ctx, table_name)
+	if errors.Is(err, ErrTableNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// provisionedThroughputDynamoDB returns the provisioned throughput of a table or
+// index, or nil if it is billed per request and has no units.
+func provisionedThroughputDynamoDB(read_units int, write_units int) *dynamodb.ProvisionedThroughput {
+	if read_units == 0 && write_units == 0 {
+		return nil
+	}
+	return &dynamodb.ProvisionedThroughput{
+		ReadCapacityUnits:  aws.Int64(int64(read_units)),
+		WriteCapacityUnits: aws.Int64(int64(write_units)),
+	}
+}
 
+// projectionDynamoDB returns the projection of an index.
+func projectionDynamoDB(index Index) *dynamodb.Projection {
+	projection := &dynamodb.Projection{
+		ProjectionType: aws.String(string(index.Projection)),
+	}
+	if len(index.ProjectionAttributes) > 0 {
+		projection.NonKeyAttributes = aws.StringSlice(index.ProjectionAttributes)
+	}
+	return projection
 }
 
 func (b *BackendDynamoDB) DeleteTables(table_names []string) error {
+	return b.DeleteTablesContext(context.Background(), table_names)
+}
+
+func (b *BackendDynamoDB) DeleteTablesContext(ctx context.Context, table_names []string) error {
 	var err error
 	for _, table_name := range table_names {
-		err = b.DeleteTable(table_name)
+		err = b.DeleteTableContext(ctx, table_name)
 		if err != nil {
-			if aerr, ok := err.(awserr.Error); ok {
-				switch aerr.Code() {
-				case dynamodb.ErrCodeResourceInUseException:
-					break
-				case dynamodb.ErrCodeResourceNotFoundException:
-					// If it doesn't exist, that's fine.  Just log and continue.
-					log.Println(dynamodb.ErrCodeResourceNotFoundException, aerr.Error())
-					err = nil
-				case dynamodb.ErrCodeLimitExceededException:
-					break
-				case dynamodb.ErrCodeInternalServerError:
-					break
-				default:
-					break
-				}
-			} else {
+			var aerr awserr.Error
+			if errors.Is(err, ErrTableNotFound) {
+				// If it doesn't exist, that's fine.  Just log and continue.
+				log.Println(err.Error())
+				err = nil
+			} else if !errors.As(err, &aerr) {
 				break
 			}
 		}
@@ -462,36 +2198,63 @@ func (b *BackendDynamoDB) DeleteTables(table_names []string) error {
 }
 
 func (b *BackendDynamoDB) DeleteTable(table_name string) error {
+	return b.DeleteTableContext(context.Background(), table_name)
+}
 
-	_, err := b.dynamodb_client.DeleteTable(&dynamodb.DeleteTableInput{
+func (b *BackendDynamoDB) DeleteTableContext(ctx context.Context, table_name string) error {
+
+	b.forgetKeys(table_name)
+
+	_, err := b.dynamodb_client.DeleteTableWithContext(ctx, &dynamodb.DeleteTableInput{
 		TableName: aws.String(table_name),
 	})
 
 	if err != nil {
-		return err
+		return wrapDynamoDBError(err)
 	}
 
-	for true {
-		result, err := b.dynamodb_client.DescribeTable(&dynamodb.DescribeTableInput{
-			TableName: aws.String(table_name),
-		})
-		if err != nil {
-			if aerr, ok := err.(awserr.Error); ok {
-				switch aerr.Code() {
-				case dynamodb.ErrCodeResourceNotFoundException:
-					return nil
-				default:
-					return err
-				}
-			} else {
-				return err
-			}
-		}
-		if *result.Table.TableStatus != "DELETING" {
-			return errors.New("Error: DynamoDB Table should have status DELETING.")
-		}
-		time.Sleep(1000 * time.Millisecond)
+	return b.waitForTableDeleted(ctx, table_name)
+}
+
+// cursorDynamoDB is the state of a continuation token for DynamoDB.
+type cursorDynamoDB struct {
+	LastEvaluatedKey map[string]*dynamodb.AttributeValue
+}
+
+// encodeCursorDynamoDB returns the continuation token for the LastEvaluatedKey of a
+// Query or Scan, or an empty token if there are no more pages.
+func encodeCursorDynamoDB(last_evaluated_key map[string]*dynamodb.AttributeValue) (string, error) {
+	if len(last_evaluated_key) == 0 {
+		return "", nil
 	}
+	return encodeCursor(cursorDynamoDB{LastEvaluatedKey: last_evaluated_key})
+}
 
-	return nil
+// wrapDynamoDBError maps the error codes returned by DynamoDB to the portable errors
+// of this package.  The original error remains available through errors.As.
+func wrapDynamoDBError(err error) error {
+	if err == nil {
+		return nil
+	}
+	aerr, ok := err.(awserr.Error)
+	if !ok {
+		return err
+	}
+	switch aerr.Code() {
+	case request.CanceledErrorCode:
+		if aerr.OrigErr() != nil {
+			return aerr.OrigErr()
+		}
+	case dynamodb.ErrCodeConditionalCheckFailedException, dynamodb.ErrCodeTransactionConflictException:
+		return &Error{Kind: ErrConflict, Err: err}
+	case dynamodb.ErrCodeResourceNotFoundException, dynamodb.ErrCodeTableNotFoundException:
+		return &Error{Kind: ErrTableNotFound, Err: err}
+	case dynamodb.ErrCodeTableAlreadyExistsException:
+		return &Error{Kind: ErrTableExists, Err: err}
+	case dynamodb.ErrCodeProvisionedThroughputExceededException, dynamodb.ErrCodeRequestLimitExceeded, dynamodb.ErrCodeLimitExceededException, "ThrottlingException":
+		return &Error{Kind: ErrThrottled, Err: err}
+	case "ValidationException", "SerializationException":
+		return &Error{Kind: ErrInvalidQuery, Err: err}
+	}
+	return err
 }
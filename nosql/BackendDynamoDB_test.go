@@ -0,0 +1,27 @@
+package nosql_test
+
+import (
+	"os"
+	"testing"
+)
+
+import (
+	"github.com/spatialcurrent/go-nosql/nosql"
+	"github.com/spatialcurrent/go-nosql/nosql/nosqltest"
+)
+
+// TestBackendDynamoDB runs the conformance suite against the database in NOSQL_DYNAMODB_DSN,
+// for example "dynamodb://us-west-2?endpoint=http://localhost:8000&wait_for_active=true".
+func TestBackendDynamoDB(t *testing.T) {
+	dsn := os.Getenv("NOSQL_DYNAMODB_DSN")
+	if len(dsn) == 0 {
+		t.Skip("NOSQL_DYNAMODB_DSN is not set")
+	}
+	nosqltest.RunConformance(t, func() nosql.Backend {
+		b, err := nosql.Open(dsn)
+		if err != nil {
+			t.Fatalf("Open returned error: %v", err)
+		}
+		return b
+	})
+}
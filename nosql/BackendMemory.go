@@ -0,0 +1,1283 @@
+package nosql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// BackendMemory is an in-memory backend for tests.  It is safe for concurrent use.
+// Items are marshaled like in BackendDynamoDB and are keyed by their "id" attribute,
+// or by the partition and sort key of the table.
+// Reads by attribute value require an index on the attribute, like the global
+// secondary indexes of DynamoDB.  Unlike DynamoDB, results are always sorted by
+// sort_fields, and by id otherwise, and the projections of indexes are only applied by
+// QueryItems.
+type BackendMemory struct {
+	mutex       sync.RWMutex
+	tables      map[string]*tableMemory
+	definitions tableDefinitions
+}
+
+type tableMemory struct {
+	indexes       []Index
+	partition_key Attribute
+	sort_key      Attribute
+	ttl_attribute string
+	billing_mode  BillingMode
+	read_units    int
+	write_units   int
+	items         map[string]map[string]*dynamodb.AttributeValue
+}
+
+func init() {
+	Register("memory", func(options map[string]string) (Backend, error) {
+		b := &BackendMemory{}
+		err := b.Connect(options)
+		if err != nil {
+			return nil, err
+		}
+		return b, nil
+	})
+	RegisterScheme("memory", func(dsn string) (Backend, error) {
+		if dsn != "memory://" {
+			return nil, &Error{Kind: ErrInvalidConfig, Err: errors.New("memory DSN does not accept parameters")}
+		}
+		return &BackendMemory{}, nil
+	})
+}
+
+func (b *BackendMemory) Type() string {
+	return "memory"
+}
+
+func (b *BackendMemory) Connect(options map[string]string) error {
+	for k := range options {
+		return &Error{Kind: ErrInvalidConfig, Err: fmt.Errorf("unknown memory option %q", k)}
+	}
+	return nil
+}
+
+// table returns the table with the given name.  The caller must hold the mutex.
+func (b *BackendMemory) table(table_name string) (*tableMemory, error) {
+	t, ok := b.tables[table_name]
+	if !ok {
+		return nil, &Error{Kind: ErrTableNotFound, Err: fmt.Errorf("table %q does not exist", table_name)}
+	}
+	return t, nil
+}
+
+// requireIndex returns an error if the attribute is not the hash key of the index
+// named "<attribute>-index".
+func (t *tableMemory) requireIndex(table_name string, attribute_name string) error {
+	if index, ok := indexByName(t.indexes, attribute_name+"-index"); !ok || index.HashKey != attribute_name {
+		return &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("table %q has no index on %q", table_name, attribute_name)}
+	}
+	return nil
+}
+
+// itemKey returns the key of an item in the items of the table, which is the id of
+// the item for tables keyed by id.  Numbers are normalized, since DynamoDB compares
+// keys by value.
+func (t *tableMemory) itemKey(table_name string, item map[string]*dynamodb.AttributeValue) (string, error) {
+	if t.partition_key == defaultPartitionKey && len(t.sort_key.Name) == 0 {
+		return itemId(item)
+	}
+	parts := make([]string, 0, 2)
+	for _, k := range []Attribute{t.partition_key, t.sort_key} {
+		if len(k.Name) == 0 {
+			continue
+		}
+		av := item[k.Name]
+		if attributeType(av) != k.Type {
+			return "", &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("key attribute %q of table %q requires a value of type %s", k.Name, table_name, k.Type)}
+		}
+		switch k.Type {
+		case AttributeTypeString:
+			if len(*av.S) == 0 {
+				return "", &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("key attribute %q of table %q is empty", k.Name, table_name)}
+			}
+			parts = append(parts, *av.S)
+		case AttributeTypeNumber:
+			n, err := addNumbers(*av.N, "0")
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, n)
+		case AttributeTypeBinary:
+			parts = append(parts, string(av.B))
+		}
+	}
+	return strings.Join(parts, "\x00"), nil
+}
+
+// requireIndexValue returns an error if the attribute is not indexed, or if the value
+// is not of the type of the attribute, which DynamoDB rejects.
+func (b *BackendMemory) requireIndexValue(t *tableMemory, table_name string, attribute_name string, value interface{}) error {
+	err := t.requireIndex(table_name, attribute_name)
+	if err != nil {
+		return err
+	}
+	av, err := marshalFilterValue(value)
+	if err != nil {
+		return err
+	}
+	if attribute_type := b.definitions.attributeType(table_name, attribute_name); attributeType(av) != attribute_type {
+		return &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("index on %q of table %q requires a value of type %s", attribute_name, table_name, attribute_type)}
+	}
+	return nil
+}
+
+// item returns the item with the given key unless it has expired.  Expired items are
+// kept, like in DynamoDB until they are deleted in the background, but are never
+// returned.  The caller must hold the mutex.
+func (t *tableMemory) item(k string) (map[string]*dynamodb.AttributeValue, bool) {
+	item, ok := t.items[k]
+	if !ok || expiredItem(item, t.ttl_attribute, time.Now()) {
+		return nil, false
+	}
+	return item, true
+}
+
+// selectItems returns the items matching the filter sorted by the sort fields, and by
+// id otherwise.  The caller must hold the mutex.
+func (t *tableMemory) selectItems(f Filter, sort_fields []string) ([]map[string]*dynamodb.AttributeValue, error) {
+	ids := make([]string, 0, len(t.items))
+	for id := range t.items {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	items := make([]map[string]*dynamodb.AttributeValue, 0, len(ids))
+	now := time.Now()
+	for _, id := range ids {
+		item := t.items[id]
+		if expiredItem(item, t.ttl_attribute, now) {
+			continue
+		}
+		ok, err := matchFilterMemory(item, f)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			items = append(items, item)
+		}
+	}
+	sortAttributeValueMaps(items, sort_fields)
+	return items, nil
+}
+
+// read selects items from a table while holding the read lock.
+func (b *BackendMemory) read(ctx context.Context, table_name string, fn func(t *tableMemory) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	t, err := b.table(table_name)
+	if err != nil {
+		return err
+	}
+	return fn(t)
+}
+
+// write modifies a table while holding the write lock.
+func (b *BackendMemory) write(ctx context.Context, table_name string, fn func(t *tableMemory) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	t, err := b.table(table_name)
+	if err != nil {
+		return err
+	}
+	return fn(t)
+}
+
+func (b *BackendMemory) DefineTables(tables []Table) error {
+	b.definitions.define(tables)
+	return nil
+}
+
+func (b *BackendMemory) CreateTables(tables []Table) error {
+	return b.CreateTablesContext(context.Background(), tables)
+}
+
+func (b *BackendMemory) CreateTablesContext(ctx context.Context, tables []Table) error {
+	b.definitions.define(tables)
+	for _, t := range tables {
+		err := b.CreateTableContext(ctx, t.Name, t.Indexes, t.ReadUnits, t.WriteUnits)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *BackendMemory) CreateTable(table_name string, indexes []string, readUnits int, writeUnits int) error {
+	return b.CreateTableContext(context.Background(), table_name, indexes, readUnits, writeUnits)
+}
+
+func (b *BackendMemory) CreateTableContext(ctx context.Context, table_name string, indexes []string, readUnits int, writeUnits int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if _, ok := b.tables[table_name]; ok {
+		return &Error{Kind: ErrTableExists, Err: fmt.Errorf("table %q already exists", table_name)}
+	}
+	if b.tables == nil {
+		b.tables = map[string]*tableMemory{}
+	}
+	throughput := b.definitions.throughput(table_name, readUnits, writeUnits)
+	err := throughput.validate(table_name)
+	if err != nil {
+		return err
+	}
+	partition_key, sort_key := b.definitions.keySchema(table_name)
+	table_indexes, err := b.definitions.indexes(table_name, indexes)
+	if err != nil {
+		return err
+	}
+	t := &tableMemory{
+		indexes:       table_indexes,
+		partition_key: partition_key,
+		sort_key:      sort_key,
+		ttl_attribute: b.definitions.ttlAttribute(table_name),
+		items:         map[string]map[string]*dynamodb.AttributeValue{},
+	}
+	t.setThroughput(throughput)
+	b.tables[table_name] = t
+	return nil
+}
+
+// setThroughput sets the billing mode and the units of the table and of its indexes.
+// Tables billed per request have no units, like in DynamoDB.
+func (t *tableMemory) setThroughput(throughput *Throughput) {
+	t.billing_mode = throughput.billingMode()
+	t.read_units = 0
+	t.write_units = 0
+	if t.billing_mode == BillingModeProvisioned {
+		t.read_units = throughput.ReadUnits
+		t.write_units = throughput.WriteUnits
+	}
+	for i, index := range t.indexes {
+		t.indexes[i] = throughput.index(index)
+	}
+}
+
+func (b *BackendMemory) UpdateTimeToLive(table_name string, ttl_attribute string) error {
+	return b.UpdateTimeToLiveContext(context.Background(), table_name, ttl_attribute)
+}
+
+// UpdateTimeToLiveContext changes the time to live attribute of a table, or disables
+// time to live if the attribute is empty.
+func (b *BackendMemory) UpdateTimeToLiveContext(ctx context.Context, table_name string, ttl_attribute string) error {
+	err := b.write(ctx, table_name, func(t *tableMemory) error {
+		t.ttl_attribute = ttl_attribute
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	b.definitions.defineTTLAttribute(table_name, ttl_attribute)
+	return nil
+}
+
+func (b *BackendMemory) UpdateThroughput(table_name string, throughput *Throughput) error {
+	return b.UpdateThroughputContext(context.Background(), table_name, throughput)
+}
+
+func (b *BackendMemory) UpdateThroughputContext(ctx context.Context, table_name string, throughput *Throughput) error {
+	err := throughput.validate(table_name)
+	if err != nil {
+		return err
+	}
+	return b.write(ctx, table_name, func(t *tableMemory) error {
+		t.setThroughput(throughput)
+		return nil
+	})
+}
+
+func (b *BackendMemory) DeleteTables(table_names []string) error {
+	return b.DeleteTablesContext(context.Background(), table_names)
+}
+
+func (b *BackendMemory) DeleteTablesContext(ctx context.Context, table_names []string) error {
+	for _, table_name := range table_names {
+		err := b.DeleteTableContext(ctx, table_name)
+		if err != nil && !errors.Is(err, ErrTableNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *BackendMemory) DeleteTable(table_name string) error {
+	return b.DeleteTableContext(context.Background(), table_name)
+}
+
+func (b *BackendMemory) DeleteTableContext(ctx context.Context, table_name string) error {
+	return b.write(ctx, table_name, func(t *tableMemory) error {
+		delete(b.tables, table_name)
+		return nil
+	})
+}
+
+func (b *BackendMemory) ListTables() ([]string, error) {
+	return b.ListTablesContext(context.Background())
+}
+
+func (b *BackendMemory) ListTablesContext(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	table_names := make([]string, 0, len(b.tables))
+	for table_name := range b.tables {
+		table_names = append(table_names, table_name)
+	}
+	sort.Strings(table_names)
+	return table_names, nil
+}
+
+func (b *BackendMemory) DescribeTable(table_name string) (*TableDescription, error) {
+	return b.DescribeTableContext(context.Background(), table_name)
+}
+
+// DescribeTableContext returns the table, which is always active.  The size of
+// in-memory tables is not estimated, so SizeBytes is 0.
+func (b *BackendMemory) DescribeTableContext(ctx context.Context, table_name string) (*TableDescription, error) {
+	var description *TableDescription
+	err := b.read(ctx, table_name, func(t *tableMemory) error {
+		description = &TableDescription{
+			Table: Table{
+				Name:             table_name,
+				ReadUnits:        t.read_units,
+				WriteUnits:       t.write_units,
+				BillingMode:      t.billing_mode,
+				TTLAttribute:     t.ttl_attribute,
+				PartitionKey:     t.partition_key,
+				SortKey:          t.sort_key,
+				SecondaryIndexes: append([]Index{}, t.indexes...),
+			},
+			Status:    TableStatusActive,
+			ItemCount: int64(len(t.items)),
+		}
+		return nil
+	})
+	return description, err
+}
+
+func (b *BackendMemory) TableExists(table_name string) (bool, error) {
+	return b.TableExistsContext(context.Background(), table_name)
+}
+
+func (b *BackendMemory) TableExistsContext(ctx context.Context, table_name string) (bool, error) {
+	err := b.read(ctx, table_name, func(t *tableMemory) error {
+		return nil
+	})
+	if errors.Is(err, ErrTableNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (b *BackendMemory) GetItems(table_name string, index_name string, sort_fields []string, items interface{}) error {
+	return b.GetItemsContext(context.Background(), table_name, index_name, sort_fields, items)
+}
+
+func (b *BackendMemory) GetItemsContext(ctx context.Context, table_name string, index_name string, sort_fields []string, items interface{}) error {
+	return b.read(ctx, table_name, func(t *tableMemory) error {
+		f, err := t.indexFilter(table_name, index_name)
+		if err != nil {
+			return err
+		}
+		results, err := t.selectItems(f, sort_fields)
+		if err != nil {
+			return err
+		}
+		return t.unmarshalListOfMaps(results, items)
+	})
+}
+
+// indexFilter returns a filter for the items present in the index, which like global
+// secondary indexes only contains the items with the indexed attribute.
+func (t *tableMemory) indexFilter(table_name string, index_name string) (Filter, error) {
+	if len(index_name) == 0 {
+		return nil, nil
+	}
+	index, ok := indexByName(t.indexes, index_name)
+	if !ok {
+		return nil, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("table %q has no index %q", table_name, index_name)}
+	}
+	if len(index.RangeKey) > 0 {
+		return And(Exists(index.HashKey), Exists(index.RangeKey)), nil
+	}
+	return Exists(index.HashKey), nil
+}
+
+// project returns the attributes of the items that are projected into the index.
+func (t *tableMemory) project(index Index, items []map[string]*dynamodb.AttributeValue) []map[string]*dynamodb.AttributeValue {
+	if index.Projection == ProjectionAll {
+		return items
+	}
+	names := append([]string{t.partition_key.Name, t.sort_key.Name, index.HashKey, index.RangeKey}, index.ProjectionAttributes...)
+	projected := make([]map[string]*dynamodb.AttributeValue, 0, len(items))
+	for _, item := range items {
+		p := map[string]*dynamodb.AttributeValue{}
+		for _, name := range names {
+			if av, ok := item[name]; ok {
+				p[name] = av
+			}
+		}
+		projected = append(projected, p)
+	}
+	return projected
+}
+
+func (b *BackendMemory) GetItemsPage(table_name string, index_name string, sort_fields []string, page_size int, token string, items interface{}) (string, error) {
+	return b.GetItemsPageContext(context.Background(), table_name, index_name, sort_fields, page_size, token, items)
+}
+
+func (b *BackendMemory) GetItemsPageContext(ctx context.Context, table_name string, index_name string, sort_fields []string, page_size int, token string, items interface{}) (string, error) {
+	next := ""
+	err := b.read(ctx, table_name, func(t *tableMemory) error {
+		f, err := t.indexFilter(table_name, index_name)
+		if err != nil {
+			return err
+		}
+		results, err := t.selectItems(f, sort_fields)
+		if err != nil {
+			return err
+		}
+		next, err = pageMemory(unmarshalExpiryItems(results, t.ttl_attribute), page_size, token, items)
+		return err
+	})
+	return next, err
+}
+
+// pageMemory unmarshals the page of results starting at the token into items and
+// returns the token for the next page.
+func pageMemory(results []map[string]*dynamodb.AttributeValue, page_size int, token string, items interface{}) (string, error) {
+	cur := cursorMemory{}
+	if len(token) > 0 {
+		err := decodeCursor(token, &cur)
+		if err != nil {
+			return "", err
+		}
+		if cur.Skip < 0 {
+			return "", &Error{Kind: ErrInvalidQuery, Err: errors.New("continuation token has negative skip")}
+		}
+	}
+	if cur.Skip > len(results) {
+		cur.Skip = len(results)
+	}
+	results = results[cur.Skip:]
+	next := ""
+	if page_size > 0 && len(results) > page_size {
+		results = results[:page_size]
+		var err error
+		next, err = encodeCursor(cursorMemory{Skip: cur.Skip + page_size})
+		if err != nil {
+			return "", err
+		}
+	}
+	return next, dynamodbattribute.UnmarshalListOfMaps(results, items)
+}
+
+// cursorMemory is the state of a continuation token for BackendMemory.
+type cursorMemory struct {
+	Skip int
+}
+
+func (b *BackendMemory) GetItemById(table_name string, id string, item interface{}) error {
+	return b.GetItemByIdContext(context.Background(), table_name, id, item)
+}
+
+func (b *BackendMemory) GetItemByIdContext(ctx context.Context, table_name string, id string, item interface{}) error {
+	return b.read(ctx, table_name, func(t *tableMemory) error {
+		err := t.requireIdKey(table_name)
+		if err != nil {
+			return err
+		}
+		result, ok := t.item(id)
+		if !ok {
+			return ErrNotFound
+		}
+		return t.unmarshalMap(result, item)
+	})
+}
+
+func (b *BackendMemory) GetItem(table_name string, key Key, item interface{}) error {
+	return b.GetItemContext(context.Background(), table_name, key, item)
+}
+
+func (b *BackendMemory) GetItemContext(ctx context.Context, table_name string, key Key, item interface{}) error {
+	return b.read(ctx, table_name, func(t *tableMemory) error {
+		k, err := t.key(table_name, key)
+		if err != nil {
+			return err
+		}
+		result, ok := t.item(k)
+		if !ok {
+			return ErrNotFound
+		}
+		return t.unmarshalMap(result, item)
+	})
+}
+
+// unmarshalMap unmarshals an item of the table, with its time to live attribute as a
+// time.
+func (t *tableMemory) unmarshalMap(item map[string]*dynamodb.AttributeValue, out interface{}) error {
+	return dynamodbattribute.UnmarshalMap(unmarshalExpiry(item, t.ttl_attribute), out)
+}
+
+// unmarshalListOfMaps unmarshals items of the table like unmarshalMap.
+func (t *tableMemory) unmarshalListOfMaps(items []map[string]*dynamodb.AttributeValue, out interface{}) error {
+	return dynamodbattribute.UnmarshalListOfMaps(unmarshalExpiryItems(items, t.ttl_attribute), out)
+}
+
+// requireIdKey returns ErrInvalidQuery unless the table is keyed by id.
+func (t *tableMemory) requireIdKey(table_name string) error {
+	return requireIdKey(table_name, t.partition_key.Name, t.sort_key.Name)
+}
+
+// key validates a key against the key schema of the table and returns the key of the
+// item in the items of the table.
+func (t *tableMemory) key(table_name string, key Key) (string, error) {
+	err := validateKey(table_name, key, t.partition_key.Name, t.sort_key.Name)
+	if err != nil {
+		return "", err
+	}
+	av, err := keyAttributeValues(key)
+	if err != nil {
+		return "", err
+	}
+	return t.itemKey(table_name, av)
+}
+
+func (b *BackendMemory) QueryItems(table_name string, query *KeyQuery, items interface{}) error {
+	return b.QueryItemsContext(context.Background(), table_name, query, items)
+}
+
+func (b *BackendMemory) QueryItemsContext(ctx context.Context, table_name string, query *KeyQuery, items interface{}) error {
+	return b.read(ctx, table_name, func(t *tableMemory) error {
+		index := Index{HashKey: t.partition_key.Name, RangeKey: t.sort_key.Name, Projection: ProjectionAll}
+		if query != nil && len(query.Index) > 0 {
+			var ok bool
+			index, ok = indexByName(t.indexes, query.Index)
+			if !ok {
+				return &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("table %q has no index %q", table_name, query.Index)}
+			}
+		}
+		err := validateKeyQuery(table_name, query, index.RangeKey)
+		if err != nil {
+			return err
+		}
+		f := Eq(index.HashKey, query.Partition)
+		if len(index.RangeKey) > 0 {
+			f = And(f, Exists(index.RangeKey))
+		}
+		if query.Sort != nil {
+			f = And(f, query.Sort)
+		}
+		sort_fields := []string{}
+		if len(index.RangeKey) > 0 {
+			if query.Descending {
+				sort_fields = append(sort_fields, "-"+index.RangeKey)
+			} else {
+				sort_fields = append(sort_fields, index.RangeKey)
+			}
+		}
+		results, err := t.selectItems(f, sort_fields)
+		if err != nil {
+			return err
+		}
+		if query.Limit > 0 && len(results) > query.Limit {
+			results = results[:query.Limit]
+		}
+		return t.unmarshalListOfMaps(t.project(index, results), items)
+	})
+}
+
+func (b *BackendMemory) GetItemsByIds(table_name string, ids []string, sort_fields []string, items interface{}) error {
+	return b.GetItemsByIdsContext(context.Background(), table_name, ids, sort_fields, items)
+}
+
+func (b *BackendMemory) GetItemsByIdsContext(ctx context.Context, table_name string, ids []string, sort_fields []string, items interface{}) error {
+	missing := []string{}
+	err := b.read(ctx, table_name, func(t *tableMemory) error {
+		err := t.requireIdKey(table_name)
+		if err != nil {
+			return err
+		}
+		var results []map[string]*dynamodb.AttributeValue
+		found := map[string]map[string]*dynamodb.AttributeValue{}
+		for _, id := range ids {
+			if item, ok := t.item(id); ok {
+				found[id] = item
+			}
+		}
+		results, missing = orderItemsByIds(ids, found, sort_fields)
+		return t.unmarshalListOfMaps(results, items)
+	})
+	if err != nil {
+		return err
+	}
+	return missingItems(missing)
+}
+
+func (b *BackendMemory) GetItemByAttributeValue(table_name string, attribute_name string, attribute_value string, item interface{}) error {
+	return b.GetItemByAttributeValueContext(context.Background(), table_name, attribute_name, attribute_value, item)
+}
+
+func (b *BackendMemory) GetItemByAttributeValueContext(ctx context.Context, table_name string, attribute_name string, attribute_value string, item interface{}) error {
+	return b.GetItemByAttributeContext(ctx, table_name, attribute_name, attribute_value, item)
+}
+
+func (b *BackendMemory) GetItemByAttribute(table_name string, attribute_name string, value interface{}, item interface{}) error {
+	return b.GetItemByAttributeContext(context.Background(), table_name, attribute_name, value, item)
+}
+
+func (b *BackendMemory) GetItemByAttributeContext(ctx context.Context, table_name string, attribute_name string, value interface{}, item interface{}) error {
+	return b.read(ctx, table_name, func(t *tableMemory) error {
+		err := b.requireIndexValue(t, table_name, attribute_name, value)
+		if err != nil {
+			return err
+		}
+		results, err := t.selectItems(Eq(attribute_name, value), []string{})
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			return ErrNotFound
+		}
+		return t.unmarshalMap(results[0], item)
+	})
+}
+
+func (b *BackendMemory) GetItemsByAttributeValue(table_name string, attribute_name string, attribute_value string, sort_fields []string, items interface{}) error {
+	return b.GetItemsByAttributeValueContext(context.Background(), table_name, attribute_name, attribute_value, sort_fields, items)
+}
+
+func (b *BackendMemory) GetItemsByAttributeValueContext(ctx context.Context, table_name string, attribute_name string, attribute_value string, sort_fields []string, items interface{}) error {
+	return b.GetItemsByAttributeContext(ctx, table_name, attribute_name, attribute_value, sort_fields, items)
+}
+
+func (b *BackendMemory) GetItemsByAttribute(table_name string, attribute_name string, value interface{}, sort_fields []string, items interface{}) error {
+	return b.GetItemsByAttributeContext(context.Background(), table_name, attribute_name, value, sort_fields, items)
+}
+
+func (b *BackendMemory) GetItemsByAttributeContext(ctx context.Context, table_name string, attribute_name string, value interface{}, sort_fields []string, items interface{}) error {
+	return b.read(ctx, table_name, func(t *tableMemory) error {
+		err := b.requireIndexValue(t, table_name, attribute_name, value)
+		if err != nil {
+			return err
+		}
+		results, err := t.selectItems(Eq(attribute_name, value), sort_fields)
+		if err != nil {
+			return err
+		}
+		return t.unmarshalListOfMaps(results, items)
+	})
+}
+
+func (b *BackendMemory) GetItemsByAttributeValuePage(table_name string, attribute_name string, attribute_value string, sort_fields []string, page_size int, token string, items interface{}) (string, error) {
+	return b.GetItemsByAttributeValuePageContext(context.Background(), table_name, attribute_name, attribute_value, sort_fields, page_size, token, items)
+}
+
+func (b *BackendMemory) GetItemsByAttributeValuePageContext(ctx context.Context, table_name string, attribute_name string, attribute_value string, sort_fields []string, page_size int, token string, items interface{}) (string, error) {
+	next := ""
+	err := b.read(ctx, table_name, func(t *tableMemory) error {
+		err := t.requireIndex(table_name, attribute_name)
+		if err != nil {
+			return err
+		}
+		results, err := t.selectItems(Eq(attribute_name, attribute_value), sort_fields)
+		if err != nil {
+			return err
+		}
+		next, err = pageMemory(unmarshalExpiryItems(results, t.ttl_attribute), page_size, token, items)
+		return err
+	})
+	return next, err
+}
+
+func (b *BackendMemory) Scan(table_name string, index_name string, sort_fields []string) Iterator {
+	return b.ScanContext(context.Background(), table_name, index_name, sort_fields)
+}
+
+func (b *BackendMemory) ScanContext(ctx context.Context, table_name string, index_name string, sort_fields []string) Iterator {
+	var results []map[string]*dynamodb.AttributeValue
+	err := b.read(ctx, table_name, func(t *tableMemory) error {
+		f, err := t.indexFilter(table_name, index_name)
+		if err != nil {
+			return err
+		}
+		selected, err := t.selectItems(f, sort_fields)
+		results = unmarshalExpiryItems(selected, t.ttl_attribute)
+		return err
+	})
+	if err != nil {
+		return &errorIterator{err: err}
+	}
+	return newIteratorMemory(ctx, results)
+}
+
+func (b *BackendMemory) Query(table_name string, attribute_name string, attribute_value string, sort_fields []string) Iterator {
+	return b.QueryContext(context.Background(), table_name, attribute_name, attribute_value, sort_fields)
+}
+
+func (b *BackendMemory) QueryContext(ctx context.Context, table_name string, attribute_name string, attribute_value string, sort_fields []string) Iterator {
+	var results []map[string]*dynamodb.AttributeValue
+	err := b.read(ctx, table_name, func(t *tableMemory) error {
+		err := t.requireIndex(table_name, attribute_name)
+		if err != nil {
+			return err
+		}
+		selected, err := t.selectItems(Eq(attribute_name, attribute_value), sort_fields)
+		results = unmarshalExpiryItems(selected, t.ttl_attribute)
+		return err
+	})
+	if err != nil {
+		return &errorIterator{err: err}
+	}
+	return newIteratorMemory(ctx, results)
+}
+
+// newIteratorMemory returns an iterator over a snapshot of the results.  Since items
+// are never modified in place, the snapshot is not affected by later writes.
+func newIteratorMemory(ctx context.Context, results []map[string]*dynamodb.AttributeValue) Iterator {
+	return &iteratorDynamoDB{
+		ctx: ctx,
+		fetch: func(ctx context.Context, exclusive_start_key map[string]*dynamodb.AttributeValue) ([]map[string]*dynamodb.AttributeValue, map[string]*dynamodb.AttributeValue, error) {
+			if err := ctx.Err(); err != nil {
+				return nil, nil, err
+			}
+			return results, nil, nil
+		},
+	}
+}
+
+func (b *BackendMemory) Find(table_name string, filter Filter, opts *FindOptions, items interface{}) error {
+	return b.FindContext(context.Background(), table_name, filter, opts, items)
+}
+
+func (b *BackendMemory) FindContext(ctx context.Context, table_name string, filter Filter, opts *FindOptions, items interface{}) error {
+	if opts == nil {
+		opts = &FindOptions{}
+	}
+	return b.read(ctx, table_name, func(t *tableMemory) error {
+		f, err := t.indexFilter(table_name, opts.IndexName)
+		if err != nil {
+			return err
+		}
+		if f != nil && filter != nil {
+			f = And(f, filter)
+		} else if filter != nil {
+			f = filter
+		}
+		results, err := t.selectItems(f, opts.SortFields)
+		if err != nil {
+			return err
+		}
+		if opts.Limit > 0 && len(results) > opts.Limit {
+			results = results[:opts.Limit]
+		}
+		return t.unmarshalListOfMaps(results, items)
+	})
+}
+
+func (b *BackendMemory) Count(table_name string, filter Filter) (int64, error) {
+	return b.CountContext(context.Background(), table_name, filter)
+}
+
+func (b *BackendMemory) CountContext(ctx context.Context, table_name string, filter Filter) (int64, error) {
+	count := int64(0)
+	err := b.read(ctx, table_name, func(t *tableMemory) error {
+		results, err := t.selectItems(filter, nil)
+		if err != nil {
+			return err
+		}
+		count = int64(len(results))
+		return nil
+	})
+	return count, err
+}
+
+func (b *BackendMemory) Exists(table_name string, id string) (bool, error) {
+	return b.ExistsContext(context.Background(), table_name, id)
+}
+
+func (b *BackendMemory) ExistsContext(ctx context.Context, table_name string, id string) (bool, error) {
+	exists := false
+	err := b.read(ctx, table_name, func(t *tableMemory) error {
+		err := t.requireIdKey(table_name)
+		if err != nil {
+			return err
+		}
+		_, exists = t.item(id)
+		return nil
+	})
+	return exists, err
+}
+
+func (b *BackendMemory) InsertItem(table_name string, item interface{}) error {
+	return b.InsertItemContext(context.Background(), table_name, item)
+}
+
+func (b *BackendMemory) InsertItemContext(ctx context.Context, table_name string, item interface{}) error {
+	return b.putItem(ctx, table_name, item, nil)
+}
+
+func (b *BackendMemory) InsertItemIfNotExists(table_name string, item interface{}) error {
+	return b.InsertItemIfNotExistsContext(context.Background(), table_name, item)
+}
+
+func (b *BackendMemory) InsertItemIfNotExistsContext(ctx context.Context, table_name string, item interface{}) error {
+	return b.putItem(ctx, table_name, item, func(id string, item map[string]*dynamodb.AttributeValue, current map[string]*dynamodb.AttributeValue) error {
+		if current != nil {
+			return ErrConflict
+		}
+		return nil
+	})
+}
+
+func (b *BackendMemory) ReplaceItem(table_name string, item interface{}) error {
+	return b.ReplaceItemContext(context.Background(), table_name, item)
+}
+
+func (b *BackendMemory) ReplaceItemContext(ctx context.Context, table_name string, item interface{}) error {
+	version_attribute := b.definitions.versionAttribute(table_name)
+	return b.putItem(ctx, table_name, item, func(id string, item map[string]*dynamodb.AttributeValue, current map[string]*dynamodb.AttributeValue) error {
+		if current == nil {
+			return ErrNotFound
+		}
+		if len(version_attribute) > 0 {
+			version, err := versionAttributeValue(item[version_attribute])
+			if err != nil {
+				return err
+			}
+			err = checkVersionMemory(table_name, id, current, version_attribute, version)
+			if err != nil {
+				return err
+			}
+			item[version_attribute] = versionAttribute(version + 1)
+		}
+		return nil
+	})
+}
+
+func (b *BackendMemory) UpsertItem(table_name string, item interface{}) error {
+	return b.UpsertItemContext(context.Background(), table_name, item)
+}
+
+func (b *BackendMemory) UpsertItemContext(ctx context.Context, table_name string, item interface{}) error {
+	return b.putItem(ctx, table_name, item, nil)
+}
+
+// putItem writes an item if check returns nil.  check is given the marshaled item,
+// which it may change, and the current item with the same id, or nil if there is none.
+func (b *BackendMemory) putItem(ctx context.Context, table_name string, item interface{}, check func(id string, item map[string]*dynamodb.AttributeValue, current map[string]*dynamodb.AttributeValue) error) error {
+	av, err := dynamodbattribute.MarshalMap(item)
+	if err != nil {
+		return &Error{Kind: ErrInvalidQuery, Err: err}
+	}
+	return b.write(ctx, table_name, func(t *tableMemory) error {
+		marshalExpiry(av, t.ttl_attribute)
+		id, err := t.itemKey(table_name, av)
+		if err != nil {
+			return err
+		}
+		if check != nil {
+			current, _ := t.item(id)
+			err := check(id, av, current)
+			if err != nil {
+				return err
+			}
+		}
+		t.items[id] = av
+		return nil
+	})
+}
+
+func (b *BackendMemory) InsertItems(table_name string, items interface{}) ([]BulkResult, error) {
+	return b.InsertItemsContext(context.Background(), table_name, items)
+}
+
+// InsertItemsContext writes the items.  Like InsertItem, existing items are
+// overwritten.
+func (b *BackendMemory) InsertItemsContext(ctx context.Context, table_name string, items interface{}) ([]BulkResult, error) {
+	return b.PutItemsContext(ctx, table_name, items)
+}
+
+func (b *BackendMemory) PutItems(table_name string, items interface{}) ([]BulkResult, error) {
+	return b.PutItemsContext(context.Background(), table_name, items)
+}
+
+func (b *BackendMemory) PutItemsContext(ctx context.Context, table_name string, items interface{}) ([]BulkResult, error) {
+	values, err := sliceItems(items)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]BulkResult, len(values))
+	err = b.write(ctx, table_name, func(t *tableMemory) error {
+		for i, item := range values {
+			results[i].Index = i
+			av, err := dynamodbattribute.MarshalMap(item)
+			if err != nil {
+				results[i].Err = &Error{Kind: ErrInvalidQuery, Err: err}
+				continue
+			}
+			marshalExpiry(av, t.ttl_attribute)
+			id, err := t.itemKey(table_name, av)
+			if err != nil {
+				results[i].Err = err
+				continue
+			}
+			if keyedById(t.partition_key.Name, t.sort_key.Name) {
+				results[i].Id = id
+			}
+			t.items[id] = av
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, bulkError(results)
+}
+
+func (b *BackendMemory) DeleteItemsByIds(table_name string, ids []string) ([]BulkResult, error) {
+	return b.DeleteItemsByIdsContext(context.Background(), table_name, ids)
+}
+
+func (b *BackendMemory) DeleteItemsByIdsContext(ctx context.Context, table_name string, ids []string) ([]BulkResult, error) {
+	results := make([]BulkResult, len(ids))
+	err := b.write(ctx, table_name, func(t *tableMemory) error {
+		err := t.requireIdKey(table_name)
+		if err != nil {
+			return err
+		}
+		for i, id := range ids {
+			results[i].Index = i
+			results[i].Id = id
+			if len(id) == 0 {
+				results[i].Err = &Error{Kind: ErrInvalidQuery, Err: errors.New("id is empty")}
+				continue
+			}
+			delete(t.items, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, bulkError(results)
+}
+
+func (b *BackendMemory) UpdateItemById(table_name string, id string, values map[string]interface{}) error {
+	return b.UpdateItemByIdContext(context.Background(), table_name, id, values)
+}
+
+// UpdateItemByIdContext sets the attributes of an item to the values, and removes the
+// attributes with nil values, creating the item if it does not exist like DynamoDB.  If
+// the table is versioned, the value of the version attribute is the expected version of
+// the item.
+func (b *BackendMemory) UpdateItemByIdContext(ctx context.Context, table_name string, id string, values map[string]interface{}) error {
+	update, err := updateFromValues(values, b.definitions.versionAttribute(table_name))
+	if err != nil {
+		return err
+	}
+	return b.UpdateItemContext(ctx, table_name, id, update)
+}
+
+func (b *BackendMemory) UpdateItem(table_name string, id string, update *Update) error {
+	return b.UpdateItemContext(context.Background(), table_name, id, update)
+}
+
+// UpdateItemContext applies the update to a copy of the item, creating the item if it
+// does not exist, like DynamoDB.
+func (b *BackendMemory) UpdateItemContext(ctx context.Context, table_name string, id string, update *Update) error {
+	version_attribute := b.definitions.versionAttribute(table_name)
+	err := update.validate(table_name, "id", version_attribute)
+	if err != nil {
+		return err
+	}
+	return b.write(ctx, table_name, func(t *tableMemory) error {
+		err := t.requireIdKey(table_name)
+		if err != nil {
+			return err
+		}
+		item, err := t.updateItem(table_name, id, update, version_attribute)
+		if err != nil {
+			return err
+		}
+		t.items[id] = item
+		return nil
+	})
+}
+
+// updateItem returns the item with the update applied, without storing it.  The
+// caller must hold the write lock.
+func (t *tableMemory) updateItem(table_name string, id string, update *Update, version_attribute string) (map[string]*dynamodb.AttributeValue, error) {
+	current, exists := t.item(id)
+	if len(version_attribute) > 0 {
+		if !exists {
+			return nil, ErrNotFound
+		}
+		err := checkVersionMemory(table_name, id, current, version_attribute, *update.Version)
+		if err != nil {
+			return nil, err
+		}
+	}
+	item := map[string]*dynamodb.AttributeValue{}
+	for k, v := range current {
+		item[k] = v
+	}
+	item["id"] = &dynamodb.AttributeValue{S: aws.String(id)}
+	err := applyUpdateMemory(item, marshalExpiryOperations(update.Operations, t.ttl_attribute))
+	if err != nil {
+		return nil, err
+	}
+	if len(version_attribute) > 0 {
+		item[version_attribute] = versionAttribute(*update.Version + 1)
+	}
+	return item, nil
+}
+
+func (b *BackendMemory) WriteTransaction(tx *Transaction) error {
+	return b.WriteTransactionContext(context.Background(), tx)
+}
+
+// WriteTransactionContext applies the operations to copies of the items while holding
+// the write lock, and only stores the items if every operation succeeded.
+func (b *BackendMemory) WriteTransactionContext(ctx context.Context, tx *Transaction) error {
+	ids, items, err := tx.items()
+	if err != nil {
+		return err
+	}
+	for _, op := range tx.Operations {
+		if op.Operator == TransactionUpdate {
+			err := op.Update.validate(op.Table, "id", b.definitions.versionAttribute(op.Table))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	tables := make([]*tableMemory, len(tx.Operations))
+	results := make([]BulkResult, len(tx.Operations))
+	for i, op := range tx.Operations {
+		t, err := b.table(op.Table)
+		if err != nil {
+			return err
+		}
+		err = t.requireIdKey(op.Table)
+		if err != nil {
+			return err
+		}
+		tables[i] = t
+		results[i].Index = i
+		results[i].Id = ids[i]
+		if op.Condition != nil {
+			current, _ := t.item(ids[i])
+			if current == nil {
+				current = map[string]*dynamodb.AttributeValue{}
+			}
+			ok, err := matchFilterMemory(current, op.Condition)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				results[i].Err = &Error{Kind: ErrConflict, Err: fmt.Errorf("condition of item %q of table %q failed", ids[i], op.Table)}
+				continue
+			}
+		}
+		if op.Operator == TransactionUpdate {
+			item, err := t.updateItem(op.Table, ids[i], op.Update, b.definitions.versionAttribute(op.Table))
+			if err != nil {
+				results[i].Err = err
+				continue
+			}
+			items[i] = item
+		}
+	}
+	err = transactionError(results)
+	if err != nil {
+		return err
+	}
+	for i, op := range tx.Operations {
+		switch op.Operator {
+		case TransactionPut:
+			marshalExpiry(items[i], tables[i].ttl_attribute)
+			tables[i].items[ids[i]] = items[i]
+		case TransactionUpdate:
+			tables[i].items[ids[i]] = items[i]
+		case TransactionDelete:
+			delete(tables[i].items, ids[i])
+		}
+	}
+	return nil
+}
+
+func (b *BackendMemory) ReadTransaction(reads []TransactionRead) error {
+	return b.ReadTransactionContext(context.Background(), reads)
+}
+
+// ReadTransactionContext reads the items while holding the read lock.
+func (b *BackendMemory) ReadTransactionContext(ctx context.Context, reads []TransactionRead) error {
+	err := validateReads(reads)
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	missing := make([]string, 0)
+	for _, r := range reads {
+		t, err := b.table(r.Table)
+		if err != nil {
+			return err
+		}
+		err = t.requireIdKey(r.Table)
+		if err != nil {
+			return err
+		}
+		item, ok := t.item(r.Id)
+		if !ok {
+			missing = append(missing, r.Id)
+			continue
+		}
+		err = t.unmarshalMap(item, r.Item)
+		if err != nil {
+			return err
+		}
+	}
+	return missingItems(missing)
+}
+
+func (b *BackendMemory) RemoveItemById(table_name string, id string) error {
+	return b.RemoveItemByIdContext(context.Background(), table_name, id)
+}
+
+func (b *BackendMemory) RemoveItemByIdContext(ctx context.Context, table_name string, id string) error {
+	return b.write(ctx, table_name, func(t *tableMemory) error {
+		err := t.requireIdKey(table_name)
+		if err != nil {
+			return err
+		}
+		delete(t.items, id)
+		return nil
+	})
+}
+
+func (b *BackendMemory) RemoveItem(table_name string, key Key) error {
+	return b.RemoveItemContext(context.Background(), table_name, key)
+}
+
+func (b *BackendMemory) RemoveItemContext(ctx context.Context, table_name string, key Key) error {
+	return b.write(ctx, table_name, func(t *tableMemory) error {
+		k, err := t.key(table_name, key)
+		if err != nil {
+			return err
+		}
+		delete(t.items, k)
+		return nil
+	})
+}
+
+func (b *BackendMemory) RemoveItemByAttributeValue(table_name string, attribute_name string, attribute_value string) error {
+	return b.RemoveItemByAttributeValueContext(context.Background(), table_name, attribute_name, attribute_value)
+}
+
+func (b *BackendMemory) RemoveItemByAttributeValueContext(ctx context.Context, table_name string, attribute_name string, attribute_value string) error {
+	return b.write(ctx, table_name, func(t *tableMemory) error {
+		if attribute_name != "id" {
+			err := t.requireIndex(table_name, attribute_name)
+			if err != nil {
+				return err
+			}
+		}
+		results, err := t.selectItems(Eq(attribute_name, attribute_value), []string{})
+		if err != nil {
+			return err
+		}
+		if len(results) > 0 {
+			k, err := t.itemKey(table_name, results[0])
+			if err != nil {
+				return err
+			}
+			delete(t.items, k)
+		}
+		return nil
+	})
+}
+
+func (b *BackendMemory) RemoveItemsByAttributeValue(table_name string, attribute_name string, attribute_value string) error {
+	return b.RemoveItemsByAttributeValueContext(context.Background(), table_name, attribute_name, attribute_value)
+}
+
+func (b *BackendMemory) RemoveItemsByAttributeValueContext(ctx context.Context, table_name string, attribute_name string, attribute_value string) error {
+	return b.write(ctx, table_name, func(t *tableMemory) error {
+		err := t.requireIndex(table_name, attribute_name)
+		if err != nil {
+			return err
+		}
+		results, err := t.selectItems(Eq(attribute_name, attribute_value), []string{})
+		if err != nil {
+			return err
+		}
+		for _, item := range results {
+			k, err := t.itemKey(table_name, item)
+			if err != nil {
+				return err
+			}
+			delete(t.items, k)
+		}
+		return nil
+	})
+}
+
+func (b *BackendMemory) RemoveAll(table_name string) error {
+	return b.RemoveAllContext(context.Background(), table_name)
+}
+
+func (b *BackendMemory) RemoveAllContext(ctx context.Context, table_name string) error {
+	return b.write(ctx, table_name, func(t *tableMemory) error {
+		t.items = map[string]map[string]*dynamodb.AttributeValue{}
+		return nil
+	})
+}
+
+// checkVersionMemory returns a VersionConflictError if the version of the current item
+// is not the given version.
+func checkVersionMemory(table_name string, id string, current map[string]*dynamodb.AttributeValue, version_attribute string, version int64) error {
+	stored, err := versionAttributeValue(current[version_attribute])
+	if err != nil {
+		return err
+	}
+	if stored != version {
+		return &VersionConflictError{Table: table_name, Id: id, Version: version}
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package nosql_test
+
+import (
+	"testing"
+)
+
+import (
+	"github.com/spatialcurrent/go-nosql/nosql"
+	"github.com/spatialcurrent/go-nosql/nosql/nosqltest"
+)
+
+func TestBackendMemory(t *testing.T) {
+	nosqltest.RunConformance(t, func() nosql.Backend {
+		return &nosql.BackendMemory{}
+	})
+}
@@ -1,7 +1,12 @@
 package nosql
 
 import (
-	"strconv"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
 )
 
 import (
@@ -13,6 +18,30 @@ type BackendMongoDB struct {
 	mongodb_session       *mgo.Session
 	mongodb_database_name string
 	limit                 int
+	definitions           tableDefinitions
+}
+
+func init() {
+	Register("mongodb", func(options map[string]string) (Backend, error) {
+		b := &BackendMongoDB{}
+		err := b.Connect(options)
+		if err != nil {
+			return nil, err
+		}
+		return b, nil
+	})
+	RegisterScheme("mongodb", func(dsn string) (Backend, error) {
+		config, err := ParseConfigMongoDB(dsn)
+		if err != nil {
+			return nil, err
+		}
+		b := &BackendMongoDB{}
+		err = b.ConnectWithConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		return b, nil
+	})
 }
 
 func (b *BackendMongoDB) Type() string {
@@ -20,18 +49,26 @@ func (b *BackendMongoDB) Type() string {
 }
 
 func (b *BackendMongoDB) Connect(options map[string]string) error {
-	mongodb_session, err := mgo.Dial(options["DatabaseUri"])
+	config, err := NewConfigMongoDB(options)
 	if err != nil {
 		return err
 	}
-	b.mongodb_session = mongodb_session
-	b.mongodb_database_name = options["DatabaseName"]
-	if limit, err := strconv.Atoi(options["Limit"]); err == nil {
-		b.limit = limit
-	} else {
-		b.limit = 1000
-	}
+	return b.ConnectWithConfig(config)
+}
 
+func (b *BackendMongoDB) ConnectWithConfig(config *ConfigMongoDB) error {
+	err := config.Validate()
+	if err != nil {
+		return err
+	}
+	mongodb_session, err := mgo.Dial(config.Uri)
+	if err != nil {
+		return err
+	}
+	b.mongodb_session = mongodb_session
+	b.mongodb_database_name = config.DatabaseName
+	b.limit = config.Limit
+	b.definitions.defineDefaultVersionAttribute(config.VersionAttribute)
 	return nil
 }
 
@@ -39,126 +76,1327 @@ func (b *BackendMongoDB) GetCollection(collection_name string) *mgo.Collection {
 	return b.mongodb_session.DB(b.mongodb_database_name).C(collection_name)
 }
 
-func (b *BackendMongoDB) GetItemById(table_name string, id string, item interface{}) error {
-	c := b.GetCollection(table_name)
-	err := c.Find(bson.M{"_id": id}).One(item)
+// copySession returns a copy of the session with the deadline of ctx, if any, applied
+// as the socket timeout.
+func (b *BackendMongoDB) copySession(ctx context.Context) (*mgo.Session, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s := b.mongodb_session.Copy()
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout := time.Until(deadline)
+		if timeout <= 0 {
+			s.Close()
+			return nil, context.DeadlineExceeded
+		}
+		s.SetSocketTimeout(timeout)
+		s.SetSyncTimeout(timeout)
+	}
+	return s, nil
+}
+
+// withCollection runs fn against a copy of the session bound to ctx.  mgo cannot
+// interrupt an operation in flight, so the deadline of ctx is applied as the socket
+// timeout and the context is checked before and after the operation.
+func (b *BackendMongoDB) withCollection(ctx context.Context, collection_name string, fn func(c *mgo.Collection) error) error {
+	return b.withDatabase(ctx, func(db *mgo.Database) error {
+		return fn(db.C(collection_name))
+	})
+}
+
+// withDatabase is like withCollection for operations on the database.
+func (b *BackendMongoDB) withDatabase(ctx context.Context, fn func(db *mgo.Database) error) error {
+	s, err := b.copySession(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	err = wrapMongoDBError(fn(s.DB(b.mongodb_database_name)))
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
 	return err
 }
 
+func (b *BackendMongoDB) GetItemById(table_name string, id string, item interface{}) error {
+	return b.GetItemByIdContext(context.Background(), table_name, id, item)
+}
+
+func (b *BackendMongoDB) GetItemByIdContext(ctx context.Context, table_name string, id string, item interface{}) error {
+	if err := b.requireIdKey(table_name); err != nil {
+		return err
+	}
+	return b.withCollection(ctx, table_name, func(c *mgo.Collection) error {
+		query, err := b.find(c, bson.M{"_id": id})
+		if err != nil {
+			return err
+		}
+		return query.One(item)
+	})
+}
+
+func (b *BackendMongoDB) GetItem(table_name string, key Key, item interface{}) error {
+	return b.GetItemContext(context.Background(), table_name, key, item)
+}
+
+func (b *BackendMongoDB) GetItemContext(ctx context.Context, table_name string, key Key, item interface{}) error {
+	selector, err := b.keySelector(table_name, key)
+	if err != nil {
+		return err
+	}
+	return b.withCollection(ctx, table_name, func(c *mgo.Collection) error {
+		query, err := b.find(c, selector)
+		if err != nil {
+			return err
+		}
+		return query.One(item)
+	})
+}
+
+// keySelector validates a key against the key schema of a table and returns the
+// selector of the item.
+func (b *BackendMongoDB) keySelector(table_name string, key Key) (bson.M, error) {
+	partition_key, sort_key := b.definitions.keySchema(table_name)
+	err := validateKey(table_name, key, partition_key.Name, sort_key.Name)
+	if err != nil {
+		return nil, err
+	}
+	selector := bson.M{}
+	for k, v := range key {
+		selector[keyNameMongoDB(k)] = v
+	}
+	return selector, nil
+}
+
+func (b *BackendMongoDB) QueryItems(table_name string, query *KeyQuery, items interface{}) error {
+	return b.QueryItemsContext(context.Background(), table_name, query, items)
+}
+
+// QueryItemsContext reads the items of a partition sorted by the sort key, or by the
+// range key of the index.  Like in DynamoDB, items without the range key of the index
+// are not in the index.
+func (b *BackendMongoDB) QueryItemsContext(ctx context.Context, table_name string, query *KeyQuery, items interface{}) error {
+	partition_key, sort_key := b.definitions.keySchema(table_name)
+	if query != nil && len(query.Index) > 0 {
+		index, ok := b.definitions.index(table_name, query.Index)
+		if !ok {
+			return &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("table %q has no index %q", table_name, query.Index)}
+		}
+		partition_key, sort_key = Attribute{Name: index.HashKey}, Attribute{Name: index.RangeKey}
+	}
+	err := validateKeyQuery(table_name, query, sort_key.Name)
+	if err != nil {
+		return err
+	}
+	conditions := []bson.M{bson.M{keyNameMongoDB(partition_key.Name): query.Partition}}
+	if len(sort_key.Name) > 0 {
+		conditions = append(conditions, bson.M{keyNameMongoDB(sort_key.Name): bson.M{"$exists": true}})
+	}
+	if query.Sort != nil {
+		sort_query, err := compileFilterMongoDB(query.Sort)
+		if err != nil {
+			return err
+		}
+		conditions = append(conditions, sort_query)
+	}
+	return b.withCollection(ctx, table_name, func(c *mgo.Collection) error {
+		mq, err := b.find(c, bson.M{"$and": conditions})
+		if err != nil {
+			return err
+		}
+		if len(sort_key.Name) > 0 {
+			if query.Descending {
+				mq = mq.Sort("-" + keyNameMongoDB(sort_key.Name))
+			} else {
+				mq = mq.Sort(keyNameMongoDB(sort_key.Name))
+			}
+		}
+		if query.Limit > 0 {
+			mq = mq.Limit(query.Limit)
+		}
+		return mq.All(items)
+	})
+}
+
 func (b *BackendMongoDB) GetItemsByIds(table_name string, ids []string, sort_fields []string, items interface{}) error {
-	c := b.GetCollection(table_name)
-	var iter *mgo.Iter
-	if len(sort_fields) > 0 {
-		iter = c.Find(bson.M{"_id": bson.M{"$in": ids}}).Sort(sort_fields...).Iter()
-	} else {
-		iter = c.Find(bson.M{"_id": bson.M{"$in": ids}}).Iter()
+	return b.GetItemsByIdsContext(context.Background(), table_name, ids, sort_fields, items)
+}
+
+// GetItemsByIdsContext reads the items with $in.  The items are returned in the order
+// of the ids, or sorted by sort_fields if any.  If some of the items do not exist, the
+// items found are still returned along with a *MissingItemsError.
+func (b *BackendMongoDB) GetItemsByIdsContext(ctx context.Context, table_name string, ids []string, sort_fields []string, items interface{}) error {
+	if err := b.requireIdKey(table_name); err != nil {
+		return err
 	}
-	err := iter.All(items)
-	return err
+	unique := uniqueIds(ids)
+	missing := []string{}
+	err := b.withCollection(ctx, table_name, func(c *mgo.Collection) error {
+		q, err := b.find(c, bson.M{"_id": bson.M{"$in": unique}})
+		if err != nil {
+			return err
+		}
+		if len(sort_fields) > 0 {
+			q = q.Sort(sort_fields...)
+		}
+		results := []bson.Raw{}
+		err = q.All(&results)
+		if err != nil {
+			return err
+		}
+
+		found := map[string]bson.Raw{}
+		order := make([]string, 0, len(results))
+		for _, raw := range results {
+			doc := struct {
+				Id string `bson:"_id"`
+			}{}
+			err := raw.Unmarshal(&doc)
+			if err != nil {
+				return err
+			}
+			found[doc.Id] = raw
+			order = append(order, doc.Id)
+		}
+
+		if len(sort_fields) == 0 {
+			order = make([]string, 0, len(found))
+			for _, id := range unique {
+				if _, ok := found[id]; ok {
+					order = append(order, id)
+				}
+			}
+		}
+		for _, id := range unique {
+			if _, ok := found[id]; !ok {
+				missing = append(missing, id)
+			}
+		}
+
+		v := reflect.ValueOf(items).Elem()
+		s := reflect.MakeSlice(v.Type(), 0, len(order))
+		for _, id := range order {
+			e := reflect.New(v.Type().Elem())
+			err := found[id].Unmarshal(e.Interface())
+			if err != nil {
+				return err
+			}
+			s = reflect.Append(s, e.Elem())
+		}
+		v.Set(s)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return missingItems(missing)
 }
 
 func (b *BackendMongoDB) GetItemByAttributeValue(table_name string, attribute_name string, attribute_value string, item interface{}) error {
-	c := b.GetCollection(table_name)
-	q := bson.M{}
-	q[attribute_name] = attribute_value
-	err := c.Find(q).One(item)
-	return err
+	return b.GetItemByAttributeValueContext(context.Background(), table_name, attribute_name, attribute_value, item)
+}
+
+func (b *BackendMongoDB) GetItemByAttributeValueContext(ctx context.Context, table_name string, attribute_name string, attribute_value string, item interface{}) error {
+	return b.GetItemByAttributeContext(ctx, table_name, attribute_name, attribute_value, item)
+}
+
+func (b *BackendMongoDB) GetItemByAttribute(table_name string, attribute_name string, value interface{}, item interface{}) error {
+	return b.GetItemByAttributeContext(context.Background(), table_name, attribute_name, value, item)
+}
+
+func (b *BackendMongoDB) GetItemByAttributeContext(ctx context.Context, table_name string, attribute_name string, value interface{}, item interface{}) error {
+	return b.withCollection(ctx, table_name, func(c *mgo.Collection) error {
+		q := bson.M{}
+		q[keyNameMongoDB(attribute_name)] = value
+		query, err := b.find(c, q)
+		if err != nil {
+			return err
+		}
+		return query.One(item)
+	})
 }
 
 func (b *BackendMongoDB) GetItemsByAttributeValue(table_name string, attribute_name string, attribute_value string, sort_fields []string, items interface{}) error {
-	c := b.GetCollection(table_name)
+	return b.GetItemsByAttributeValueContext(context.Background(), table_name, attribute_name, attribute_value, sort_fields, items)
+}
+
+func (b *BackendMongoDB) GetItemsByAttributeValueContext(ctx context.Context, table_name string, attribute_name string, attribute_value string, sort_fields []string, items interface{}) error {
+	return b.GetItemsByAttributeContext(ctx, table_name, attribute_name, attribute_value, sort_fields, items)
+}
+
+func (b *BackendMongoDB) GetItemsByAttribute(table_name string, attribute_name string, value interface{}, sort_fields []string, items interface{}) error {
+	return b.GetItemsByAttributeContext(context.Background(), table_name, attribute_name, value, sort_fields, items)
+}
+
+func (b *BackendMongoDB) GetItemsByAttributeContext(ctx context.Context, table_name string, attribute_name string, value interface{}, sort_fields []string, items interface{}) error {
+	return b.withCollection(ctx, table_name, func(c *mgo.Collection) error {
+		q := bson.M{}
+		q[keyNameMongoDB(attribute_name)] = value
+		query, err := b.find(c, q)
+		if err != nil {
+			return err
+		}
+		if len(sort_fields) > 0 {
+			query = query.Sort(sort_fields...)
+		}
+		return query.Limit(b.limit).All(items)
+	})
+}
+
+func (b *BackendMongoDB) GetItemsByAttributeValuePage(table_name string, attribute_name string, attribute_value string, sort_fields []string, page_size int, token string, items interface{}) (string, error) {
+	return b.GetItemsByAttributeValuePageContext(context.Background(), table_name, attribute_name, attribute_value, sort_fields, page_size, token, items)
+}
+
+func (b *BackendMongoDB) GetItemsByAttributeValuePageContext(ctx context.Context, table_name string, attribute_name string, attribute_value string, sort_fields []string, page_size int, token string, items interface{}) (string, error) {
 	q := bson.M{}
-	q[attribute_name] = attribute_value
-	var iter *mgo.Iter
-	if len(sort_fields) > 0 {
-		iter = c.Find(q).Sort(sort_fields...).Limit(b.limit).Iter()
-	} else {
-		iter = c.Find(q).Limit(b.limit).Iter()
-	}
-	err := iter.All(items)
-	return err
+	q[keyNameMongoDB(attribute_name)] = attribute_value
+	return b.getPage(ctx, table_name, q, sort_fields, page_size, token, items)
 }
 
 func (b *BackendMongoDB) GetItems(table_name string, index_name string, sort_fields []string, items interface{}) error {
-	c := b.GetCollection(table_name)
-	var iter *mgo.Iter
+	return b.GetItemsContext(context.Background(), table_name, index_name, sort_fields, items)
+}
+
+func (b *BackendMongoDB) GetItemsContext(ctx context.Context, table_name string, index_name string, sort_fields []string, items interface{}) error {
+	return b.withCollection(ctx, table_name, func(c *mgo.Collection) error {
+		query, err := b.find(c, nil)
+		if err != nil {
+			return err
+		}
+		if len(sort_fields) > 0 {
+			query = query.Sort(sort_fields...)
+		}
+		return query.Limit(b.limit).All(items)
+	})
+}
+
+func (b *BackendMongoDB) GetItemsPage(table_name string, index_name string, sort_fields []string, page_size int, token string, items interface{}) (string, error) {
+	return b.GetItemsPageContext(context.Background(), table_name, index_name, sort_fields, page_size, token, items)
+}
+
+func (b *BackendMongoDB) GetItemsPageContext(ctx context.Context, table_name string, index_name string, sort_fields []string, page_size int, token string, items interface{}) (string, error) {
+	return b.getPage(ctx, table_name, nil, sort_fields, page_size, token, items)
+}
+
+// getPage reads one page of the items matching q.  The continuation token stores the
+// number of items to skip.  The sort always ends with _id, so that pages are stable.
+func (b *BackendMongoDB) getPage(ctx context.Context, table_name string, q interface{}, sort_fields []string, page_size int, token string, items interface{}) (string, error) {
+	cur := cursorMongoDB{}
+	if len(token) > 0 {
+		err := decodeCursor(token, &cur)
+		if err != nil {
+			return "", err
+		}
+		if cur.Skip < 0 {
+			return "", &Error{Kind: ErrInvalidQuery, Err: errors.New("continuation token has negative skip")}
+		}
+	}
+	if page_size <= 0 {
+		page_size = b.limit
+	}
+	sort := append([]string{}, sort_fields...)
+	if !containsSortField(sort, "_id") {
+		sort = append(sort, "_id")
+	}
+	err := b.withCollection(ctx, table_name, func(c *mgo.Collection) error {
+		query, err := b.find(c, q)
+		if err != nil {
+			return err
+		}
+		return query.Sort(sort...).Skip(cur.Skip).Limit(page_size).All(items)
+	})
+	if err != nil {
+		return "", err
+	}
+	if reflect.Indirect(reflect.ValueOf(items)).Len() < page_size {
+		return "", nil
+	}
+	return encodeCursor(cursorMongoDB{Skip: cur.Skip + page_size})
+}
+
+func (b *BackendMongoDB) Scan(table_name string, index_name string, sort_fields []string) Iterator {
+	return b.ScanContext(context.Background(), table_name, index_name, sort_fields)
+}
+
+func (b *BackendMongoDB) ScanContext(ctx context.Context, table_name string, index_name string, sort_fields []string) Iterator {
+	return b.iterate(ctx, table_name, nil, sort_fields)
+}
+
+func (b *BackendMongoDB) Query(table_name string, attribute_name string, attribute_value string, sort_fields []string) Iterator {
+	return b.QueryContext(context.Background(), table_name, attribute_name, attribute_value, sort_fields)
+}
+
+func (b *BackendMongoDB) QueryContext(ctx context.Context, table_name string, attribute_name string, attribute_value string, sort_fields []string) Iterator {
+	q := bson.M{}
+	q[keyNameMongoDB(attribute_name)] = attribute_value
+	return b.iterate(ctx, table_name, q, sort_fields)
+}
+
+func (b *BackendMongoDB) iterate(ctx context.Context, table_name string, q interface{}, sort_fields []string) Iterator {
+	s, err := b.copySession(ctx)
+	if err != nil {
+		return &errorIterator{err: err}
+	}
+	query, err := b.find(s.DB(b.mongodb_database_name).C(table_name), q)
+	if err != nil {
+		s.Close()
+		return &errorIterator{err: wrapMongoDBError(err)}
+	}
 	if len(sort_fields) > 0 {
-		iter = c.Find(nil).Sort(sort_fields...).Limit(b.limit).Iter()
-	} else {
-		iter = c.Find(nil).Limit(b.limit).Iter()
+		query = query.Sort(sort_fields...)
 	}
-	err := iter.All(items)
-	return err
+	return &iteratorMongoDB{ctx: ctx, session: s, iter: query.Iter()}
+}
+
+func (b *BackendMongoDB) Find(table_name string, filter Filter, opts *FindOptions, items interface{}) error {
+	return b.FindContext(context.Background(), table_name, filter, opts, items)
+}
+
+func (b *BackendMongoDB) FindContext(ctx context.Context, table_name string, filter Filter, opts *FindOptions, items interface{}) error {
+	if opts == nil {
+		opts = &FindOptions{}
+	}
+	q, err := compileFilterMongoDB(filter)
+	if err != nil {
+		return err
+	}
+	return b.withCollection(ctx, table_name, func(c *mgo.Collection) error {
+		query, err := b.find(c, q)
+		if err != nil {
+			return err
+		}
+		if len(opts.SortFields) > 0 {
+			query = query.Sort(opts.SortFields...)
+		}
+		if opts.Limit > 0 {
+			query = query.Limit(opts.Limit)
+		}
+		return query.All(items)
+	})
+}
+
+func (b *BackendMongoDB) Count(table_name string, filter Filter) (int64, error) {
+	return b.CountContext(context.Background(), table_name, filter)
+}
+
+func (b *BackendMongoDB) CountContext(ctx context.Context, table_name string, filter Filter) (int64, error) {
+	q, err := compileFilterMongoDB(filter)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	err = b.withCollection(ctx, table_name, func(c *mgo.Collection) error {
+		query, err := b.find(c, q)
+		if err != nil {
+			return err
+		}
+		count, err = query.Count()
+		return err
+	})
+	return int64(count), err
+}
+
+func (b *BackendMongoDB) Exists(table_name string, id string) (bool, error) {
+	return b.ExistsContext(context.Background(), table_name, id)
+}
+
+func (b *BackendMongoDB) ExistsContext(ctx context.Context, table_name string, id string) (bool, error) {
+	if err := b.requireIdKey(table_name); err != nil {
+		return false, err
+	}
+	count := 0
+	err := b.withCollection(ctx, table_name, func(c *mgo.Collection) error {
+		query, err := b.find(c, bson.M{"_id": id})
+		if err != nil {
+			return err
+		}
+		count, err = query.Limit(1).Count()
+		return err
+	})
+	return count > 0, err
 }
 
 func (b *BackendMongoDB) RemoveItemById(table_name string, id string) error {
-	c := b.GetCollection(table_name)
-	c.Remove(bson.M{"_id": id})
-	return nil
+	return b.RemoveItemByIdContext(context.Background(), table_name, id)
+}
+
+func (b *BackendMongoDB) RemoveItemByIdContext(ctx context.Context, table_name string, id string) error {
+	if err := b.requireIdKey(table_name); err != nil {
+		return err
+	}
+	err := b.withCollection(ctx, table_name, func(c *mgo.Collection) error {
+		return c.Remove(bson.M{"_id": id})
+	})
+	if errors.Is(err, ErrNotFound) {
+		// Like DynamoDB, removing a missing item is not an error.
+		return nil
+	}
+	return err
+}
+
+func (b *BackendMongoDB) RemoveItem(table_name string, key Key) error {
+	return b.RemoveItemContext(context.Background(), table_name, key)
+}
+
+func (b *BackendMongoDB) RemoveItemContext(ctx context.Context, table_name string, key Key) error {
+	selector, err := b.keySelector(table_name, key)
+	if err != nil {
+		return err
+	}
+	err = b.withCollection(ctx, table_name, func(c *mgo.Collection) error {
+		return c.Remove(selector)
+	})
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
 }
 
 func (b *BackendMongoDB) RemoveItemByAttributeValue(table_name string, attribute_name string, attribute_value string) error {
-	c := b.GetCollection(table_name)
-	q := bson.M{}
-	q[attribute_name] = attribute_value
-	c.Remove(q)
-	return nil
+	return b.RemoveItemByAttributeValueContext(context.Background(), table_name, attribute_name, attribute_value)
+}
+
+func (b *BackendMongoDB) RemoveItemByAttributeValueContext(ctx context.Context, table_name string, attribute_name string, attribute_value string) error {
+	return b.withCollection(ctx, table_name, func(c *mgo.Collection) error {
+		q := bson.M{}
+		q[keyNameMongoDB(attribute_name)] = attribute_value
+		err := c.Remove(q)
+		if err == mgo.ErrNotFound {
+			return nil
+		}
+		return err
+	})
 }
 
 func (b *BackendMongoDB) RemoveItemsByAttributeValue(table_name string, attribute_name string, attribute_value string) error {
-	c := b.GetCollection(table_name)
-	q := bson.M{}
-	q[attribute_name] = attribute_value
-	c.Remove(q)
-	return nil
+	return b.RemoveItemsByAttributeValueContext(context.Background(), table_name, attribute_name, attribute_value)
+}
+
+func (b *BackendMongoDB) RemoveItemsByAttributeValueContext(ctx context.Context, table_name string, attribute_name string, attribute_value string) error {
+	return b.withCollection(ctx, table_name, func(c *mgo.Collection) error {
+		q := bson.M{}
+		q[keyNameMongoDB(attribute_name)] = attribute_value
+		_, err := c.RemoveAll(q)
+		return err
+	})
 }
 
 func (b *BackendMongoDB) RemoveAll(table_name string) error {
-	c := b.GetCollection(table_name)
-	_, err := c.RemoveAll(nil)
-	return err
+	return b.RemoveAllContext(context.Background(), table_name)
+}
+
+func (b *BackendMongoDB) RemoveAllContext(ctx context.Context, table_name string) error {
+	return b.withCollection(ctx, table_name, func(c *mgo.Collection) error {
+		_, err := c.RemoveAll(nil)
+		return err
+	})
 }
 
 func (b *BackendMongoDB) InsertItem(table_name string, item interface{}) error {
-	c := b.GetCollection(table_name)
-	err := c.Insert(item)
-	items := make([]bson.M, 0)
-	iter := c.Find(nil).Limit(b.limit).Iter()
-	err = iter.All(&items)
-	return err
+	return b.InsertItemContext(context.Background(), table_name, item)
+}
+
+func (b *BackendMongoDB) InsertItemContext(ctx context.Context, table_name string, item interface{}) error {
+	return b.withCollection(ctx, table_name, func(c *mgo.Collection) error {
+		return c.Insert(item)
+	})
+}
+
+func (b *BackendMongoDB) InsertItemIfNotExists(table_name string, item interface{}) error {
+	return b.InsertItemIfNotExistsContext(context.Background(), table_name, item)
+}
+
+// InsertItemIfNotExistsContext inserts the item unless an item with the same _id
+// exists, in which case it returns ErrConflict.
+func (b *BackendMongoDB) InsertItemIfNotExistsContext(ctx context.Context, table_name string, item interface{}) error {
+	return b.withCollection(ctx, table_name, func(c *mgo.Collection) error {
+		return c.Insert(item)
+	})
+}
+
+func (b *BackendMongoDB) ReplaceItem(table_name string, item interface{}) error {
+	return b.ReplaceItemContext(context.Background(), table_name, item)
+}
+
+// ReplaceItemContext replaces an existing item, or returns ErrNotFound if no item with
+// the same key exists.  If the table is versioned, the item is only replaced if the
+// stored version equals the version of the item, and the version is incremented.
+func (b *BackendMongoDB) ReplaceItemContext(ctx context.Context, table_name string, item interface{}) error {
+	selector, id, err := b.itemSelector(table_name, item)
+	if err != nil {
+		return err
+	}
+	version_attribute := b.definitions.versionAttribute(table_name)
+	if len(version_attribute) == 0 {
+		return b.withCollection(ctx, table_name, func(c *mgo.Collection) error {
+			return c.Update(selector, item)
+		})
+	}
+
+	data, err := bson.Marshal(item)
+	if err != nil {
+		return &Error{Kind: ErrInvalidQuery, Err: err}
+	}
+	doc := bson.M{}
+	err = bson.Unmarshal(data, &doc)
+	if err != nil {
+		return &Error{Kind: ErrInvalidQuery, Err: err}
+	}
+	version, err := versionValue(doc[version_attribute])
+	if err != nil {
+		return err
+	}
+	doc[version_attribute] = version + 1
+
+	return b.withCollection(ctx, table_name, func(c *mgo.Collection) error {
+		err := c.Update(versionSelectorMongoDB(selector, version_attribute, version), doc)
+		return versionErrorMongoDB(c, table_name, selector, id, version, err)
+	})
+}
+
+// versionSelectorMongoDB returns the selector of the item at the version.  Version 0
+// also matches items without the attribute.
+func versionSelectorMongoDB(selector bson.M, version_attribute string, version int64) bson.M {
+	versioned := bson.M{}
+	for k, v := range selector {
+		versioned[k] = v
+	}
+	if version == 0 {
+		versioned[version_attribute] = bson.M{"$in": []interface{}{0, nil}}
+	} else {
+		versioned[version_attribute] = version
+	}
+	return versioned
+}
+
+// versionErrorMongoDB returns the error of a versioned write.  If no item matched the
+// versioned selector, it returns mgo.ErrNotFound if the item does not exist, and a
+// VersionConflictError otherwise.
+func versionErrorMongoDB(c *mgo.Collection, table_name string, selector bson.M, id string, version int64, err error) error {
+	if err != mgo.ErrNotFound {
+		return err
+	}
+	n, err := c.Find(selector).Count()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return mgo.ErrNotFound
+	}
+	return &VersionConflictError{Table: table_name, Id: id, Version: version}
+}
+
+func (b *BackendMongoDB) UpsertItem(table_name string, item interface{}) error {
+	return b.UpsertItemContext(context.Background(), table_name, item)
+}
+
+// UpsertItemContext writes the item, replacing any item with the same key.
+func (b *BackendMongoDB) UpsertItemContext(ctx context.Context, table_name string, item interface{}) error {
+	selector, _, err := b.itemSelector(table_name, item)
+	if err != nil {
+		return err
+	}
+	return b.withCollection(ctx, table_name, func(c *mgo.Collection) error {
+		_, err := c.Upsert(selector, item)
+		return err
+	})
+}
+
+func (b *BackendMongoDB) InsertItems(table_name string, items interface{}) ([]BulkResult, error) {
+	return b.InsertItemsContext(context.Background(), table_name, items)
+}
+
+// InsertItemsContext inserts the items with an unordered bulk operation.  Like
+// InsertItem, items that already exist fail with ErrConflict.
+func (b *BackendMongoDB) InsertItemsContext(ctx context.Context, table_name string, items interface{}) ([]BulkResult, error) {
+	values, err := sliceItems(items)
+	if err != nil {
+		return nil, err
+	}
+	return b.bulkWrite(ctx, table_name, len(values), func(bulk *mgo.Bulk, i int) (string, error) {
+		_, id, err := b.itemSelector(table_name, values[i])
+		if err != nil {
+			return "", err
+		}
+		bulk.Insert(values[i])
+		return id, nil
+	})
+}
+
+func (b *BackendMongoDB) PutItems(table_name string, items interface{}) ([]BulkResult, error) {
+	return b.PutItemsContext(context.Background(), table_name, items)
+}
+
+// PutItemsContext inserts or replaces the items with an unordered bulk operation.
+func (b *BackendMongoDB) PutItemsContext(ctx context.Context, table_name string, items interface{}) ([]BulkResult, error) {
+	values, err := sliceItems(items)
+	if err != nil {
+		return nil, err
+	}
+	return b.bulkWrite(ctx, table_name, len(values), func(bulk *mgo.Bulk, i int) (string, error) {
+		selector, id, err := b.itemSelector(table_name, values[i])
+		if err != nil {
+			return "", err
+		}
+		bulk.Upsert(selector, values[i])
+		return id, nil
+	})
+}
+
+func (b *BackendMongoDB) DeleteItemsByIds(table_name string, ids []string) ([]BulkResult, error) {
+	return b.DeleteItemsByIdsContext(context.Background(), table_name, ids)
+}
+
+// DeleteItemsByIdsContext removes the items with an unordered bulk operation.  Like
+// RemoveItemById, removing a missing item is not an error.
+func (b *BackendMongoDB) DeleteItemsByIdsContext(ctx context.Context, table_name string, ids []string) ([]BulkResult, error) {
+	if err := b.requireIdKey(table_name); err != nil {
+		return nil, err
+	}
+	return b.bulkWrite(ctx, table_name, len(ids), func(bulk *mgo.Bulk, i int) (string, error) {
+		if len(ids[i]) == 0 {
+			return "", &Error{Kind: ErrInvalidQuery, Err: errors.New("id is empty")}
+		}
+		bulk.Remove(bson.M{"_id": ids[i]})
+		return ids[i], nil
+	})
+}
+
+// bulkWrite runs an unordered bulk operation with the operations added by add for
+// each of the n items, and reports the errors of the operations in the results.
+func (b *BackendMongoDB) bulkWrite(ctx context.Context, table_name string, n int, add func(bulk *mgo.Bulk, i int) (string, error)) ([]BulkResult, error) {
+	results := make([]BulkResult, n)
+	// The index of the item of each operation.
+	operations := make([]int, 0, n)
+	err := b.withCollection(ctx, table_name, func(c *mgo.Collection) error {
+		bulk := c.Bulk()
+		bulk.Unordered()
+		for i := range results {
+			results[i].Index = i
+			id, err := add(bulk, i)
+			results[i].Id = id
+			if err != nil {
+				results[i].Err = err
+				continue
+			}
+			operations = append(operations, i)
+		}
+		if len(operations) == 0 {
+			return nil
+		}
+		_, err := bulk.Run()
+		return err
+	})
+	if err != nil {
+		var berr *mgo.BulkError
+		if !errors.As(err, &berr) {
+			for _, i := range operations {
+				results[i].Err = err
+			}
+			return results, err
+		}
+		for _, c := range berr.Cases() {
+			if c.Index < 0 || c.Index >= len(operations) {
+				// The operation is unknown, so every operation may have failed.
+				for _, i := range operations {
+					results[i].Err = wrapMongoDBError(c.Err)
+				}
+				continue
+			}
+			results[operations[c.Index]].Err = wrapMongoDBError(c.Err)
+		}
+	}
+	return results, bulkError(results)
+}
+
+// requireIdKey returns ErrInvalidQuery unless the table is keyed by id.
+func (b *BackendMongoDB) requireIdKey(table_name string) error {
+	partition_key, sort_key := b.definitions.keySchema(table_name)
+	return requireIdKey(table_name, partition_key.Name, sort_key.Name)
+}
+
+// itemSelector returns the selector of an item, and its _id if the table is keyed by
+// id.  The _id of the items of tables with a composite key is generated, so they are
+// selected by their partition key and sort key instead.
+func (b *BackendMongoDB) itemSelector(table_name string, item interface{}) (bson.M, string, error) {
+	partition_key, sort_key := b.definitions.keySchema(table_name)
+	if keyedById(partition_key.Name, sort_key.Name) {
+		id, err := itemIdMongoDB(item)
+		if err != nil {
+			return nil, "", err
+		}
+		return bson.M{"_id": id}, id, nil
+	}
+	data, err := bson.Marshal(item)
+	if err != nil {
+		return nil, "", &Error{Kind: ErrInvalidQuery, Err: err}
+	}
+	doc := bson.M{}
+	err = bson.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, "", &Error{Kind: ErrInvalidQuery, Err: err}
+	}
+	key := Key{}
+	for _, k := range []Attribute{partition_key, sort_key} {
+		if v, ok := doc[keyNameMongoDB(k.Name)]; ok && len(k.Name) > 0 {
+			key[k.Name] = v
+		}
+	}
+	selector, err := b.keySelector(table_name, key)
+	if err != nil {
+		return nil, "", err
+	}
+	return selector, "", nil
+}
+
+// itemIdMongoDB returns the _id of an item.
+func itemIdMongoDB(item interface{}) (string, error) {
+	data, err := bson.Marshal(item)
+	if err != nil {
+		return "", &Error{Kind: ErrInvalidQuery, Err: err}
+	}
+	doc := struct {
+		Id string `bson:"_id"`
+	}{}
+	err = bson.Unmarshal(data, &doc)
+	if err != nil || len(doc.Id) == 0 {
+		return "", &Error{Kind: ErrInvalidQuery, Err: errors.New("item is missing a string _id attribute")}
+	}
+	return doc.Id, nil
 }
 
 func (b *BackendMongoDB) UpdateItemById(table_name string, id string, values map[string]interface{}) error {
-	c := b.GetCollection(table_name)
-	u := bson.M{}
-	for k, v := range values {
-		u[k] = v
+	return b.UpdateItemByIdContext(context.Background(), table_name, id, values)
+}
+
+// UpdateItemByIdContext sets the attributes of an item to the values, and removes the
+// attributes with nil values.  If the table is versioned, the value of the version
+// attribute is the expected version of the item.
+func (b *BackendMongoDB) UpdateItemByIdContext(ctx context.Context, table_name string, id string, values map[string]interface{}) error {
+	update, err := updateFromValues(values, b.definitions.versionAttribute(table_name))
+	if err != nil {
+		return err
 	}
-	c.Update(bson.M{"_id": bson.ObjectIdHex(id)}, bson.M{"$set": u})
+	return b.UpdateItemContext(ctx, table_name, id, update)
+}
+
+func (b *BackendMongoDB) UpdateItem(table_name string, id string, update *Update) error {
+	return b.UpdateItemContext(context.Background(), table_name, id, update)
+}
+
+// UpdateItemContext applies the update to an item, creating the item if it does not
+// exist, like DynamoDB.  If the table is versioned, the item must exist at the version
+// of the update, and the version is incremented.  The SetIfNotExists operations set the
+// attributes that the item does not have when it is read, in the versioned update.
+// Otherwise they are applied to existing items in separate updates, so unlike
+// if_not_exists in DynamoDB they are not atomic with the rest of the update.
+func (b *BackendMongoDB) UpdateItemContext(ctx context.Context, table_name string, id string, update *Update) error {
+	if err := b.requireIdKey(table_name); err != nil {
+		return err
+	}
+	version_attribute := b.definitions.versionAttribute(table_name)
+	err := update.validate(table_name, "_id", version_attribute)
+	if err != nil {
+		return err
+	}
+	u, err := compileUpdateMongoDB(update.Operations)
+	if err != nil {
+		return err
+	}
+	return b.withCollection(ctx, table_name, func(c *mgo.Collection) error {
+		if len(version_attribute) > 0 {
+			err := setIfNotExistsMongoDB(c, id, u)
+			if err != nil {
+				return err
+			}
+			inc, ok := u["$inc"].(bson.M)
+			if !ok {
+				inc = bson.M{}
+				u["$inc"] = inc
+			}
+			inc[version_attribute] = 1
+			err = c.Update(versionSelectorMongoDB(bson.M{"_id": id}, version_attribute, *update.Version), u)
+			return versionErrorMongoDB(c, table_name, bson.M{"_id": id}, id, *update.Version, err)
+		}
+		_, err := c.Upsert(bson.M{"_id": id}, u)
+		if err != nil {
+			return err
+		}
+		for _, op := range update.Operations {
+			if op.Operator != UpdateSetIfNotExists {
+				continue
+			}
+			err := c.Update(bson.M{"_id": id, op.Attribute: bson.M{"$exists": false}}, bson.M{"$set": bson.M{op.Attribute: op.Value}})
+			if err != nil && err != mgo.ErrNotFound {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// setIfNotExistsMongoDB moves the $setOnInsert attributes of a versioned update, which
+// never inserts, to $set if the item does not have them.  The version check of the
+// update fails if the item changed since it was read.
+func setIfNotExistsMongoDB(c *mgo.Collection, id string, u bson.M) error {
+	set_on_insert, ok := u["$setOnInsert"].(bson.M)
+	if !ok {
+		return nil
+	}
+	delete(u, "$setOnInsert")
+	selection := bson.M{}
+	for k := range set_on_insert {
+		selection[k] = 1
+	}
+	current := bson.M{}
+	err := c.FindId(id).Select(selection).One(&current)
+	if err != nil && err != mgo.ErrNotFound {
+		return err
+	}
+	for k, v := range set_on_insert {
+		if hasPathMongoDB(current, k) {
+			continue
+		}
+		set, ok := u["$set"].(bson.M)
+		if !ok {
+			set = bson.M{}
+			u["$set"] = set
+		}
+		set[k] = v
+	}
+	return nil
+}
+
+// hasPathMongoDB returns true if a document has an attribute at the dotted path.
+func hasPathMongoDB(doc bson.M, path string) bool {
+	names := strings.Split(path, ".")
+	for _, name := range names[:len(names)-1] {
+		next, ok := doc[name].(bson.M)
+		if !ok {
+			return false
+		}
+		doc = next
+	}
+	_, ok := doc[names[len(names)-1]]
+	return ok
+}
+
+func (b *BackendMongoDB) WriteTransaction(tx *Transaction) error {
+	return b.WriteTransactionContext(context.Background(), tx)
+}
+
+// WriteTransactionContext returns ErrNotSupported, since the mgo driver does not
+// support multi-document transactions.
+func (b *BackendMongoDB) WriteTransactionContext(ctx context.Context, tx *Transaction) error {
+	return &Error{Kind: ErrNotSupported, Err: errors.New("the mgo driver does not support multi-document transactions")}
+}
+
+func (b *BackendMongoDB) ReadTransaction(reads []TransactionRead) error {
+	return b.ReadTransactionContext(context.Background(), reads)
+}
+
+// ReadTransactionContext returns ErrNotSupported, since the mgo driver does not
+// support multi-document transactions.
+func (b *BackendMongoDB) ReadTransactionContext(ctx context.Context, reads []TransactionRead) error {
+	return &Error{Kind: ErrNotSupported, Err: errors.New("the mgo driver does not support multi-document transactions")}
+}
+
+func (b *BackendMongoDB) DefineTables(tables []Table) error {
+	b.definitions.define(tables)
 	return nil
 }
 
 func (b *BackendMongoDB) CreateTables(tables []Table) error {
+	return b.CreateTablesContext(context.Background(), tables)
+}
+
+func (b *BackendMongoDB) CreateTablesContext(ctx context.Context, tables []Table) error {
+	b.definitions.define(tables)
 	for _, t := range tables {
-		b.CreateTable(t.Name, t.Indexes, t.ReadUnits, t.WriteUnits)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := b.CreateTableContext(ctx, t.Name, t.Indexes, t.ReadUnits, t.WriteUnits)
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
 func (b *BackendMongoDB) CreateTable(table_name string, indexes []string, readUnits int, writeUnits int) error {
-	// MongoDB tables are automatically created when adding the first item.
+	return b.CreateTableContext(context.Background(), table_name, indexes, readUnits, writeUnits)
+}
+
+func (b *BackendMongoDB) CreateTableContext(ctx context.Context, table_name string, indexes []string, readUnits int, writeUnits int) error {
+	// MongoDB tables are automatically created when adding the first item, but are
+	// created explicitly so that existing tables are reported like in DynamoDB.
+	// Tables with a composite key have a unique index on the key, since the _id of
+	// their items is generated.
+	partition_key, sort_key := b.definitions.keySchema(table_name)
+	table_indexes, err := b.definitions.indexes(table_name, indexes)
+	if err != nil {
+		return err
+	}
+	ttl_attribute := b.definitions.ttlAttribute(table_name)
+	return b.withCollection(ctx, table_name, func(c *mgo.Collection) error {
+		err := c.Create(&mgo.CollectionInfo{})
+		if err != nil {
+			return err
+		}
+		if partition_key != defaultPartitionKey || len(sort_key.Name) > 0 {
+			err := c.EnsureIndex(mgo.Index{Key: keyMongoDB(partition_key.Name, sort_key.Name), Unique: true})
+			if err != nil {
+				return err
+			}
+		}
+		for _, index := range table_indexes {
+			err := c.EnsureIndex(mgo.Index{
+				Name:        index.Name,
+				Key:         keyMongoDB(index.HashKey, index.RangeKey),
+				Unique:      index.Unique,
+				Sparse:      index.Sparse,
+				ExpireAfter: index.ExpireAfter,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if len(ttl_attribute) > 0 {
+			return ensureTTLIndexMongoDB(c, ttl_attribute)
+		}
+		return nil
+	})
+}
+
+func (b *BackendMongoDB) UpdateThroughput(table_name string, throughput *Throughput) error {
+	return b.UpdateThroughputContext(context.Background(), table_name, throughput)
+}
+
+// UpdateThroughputContext only validates the throughput and checks that the table
+// exists, since MongoDB has no throughput.
+func (b *BackendMongoDB) UpdateThroughputContext(ctx context.Context, table_name string, throughput *Throughput) error {
+	err := throughput.validate(table_name)
+	if err != nil {
+		return err
+	}
+	exists, err := b.TableExistsContext(ctx, table_name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return &Error{Kind: ErrTableNotFound, Err: fmt.Errorf("table %q does not exist", table_name)}
+	}
 	return nil
 }
 
+func (b *BackendMongoDB) UpdateTimeToLive(table_name string, ttl_attribute string) error {
+	return b.UpdateTimeToLiveContext(context.Background(), table_name, ttl_attribute)
+}
+
+// UpdateTimeToLiveContext replaces the TTL index of a collection with an index on the
+// attribute, or drops it if the attribute is empty.
+func (b *BackendMongoDB) UpdateTimeToLiveContext(ctx context.Context, table_name string, ttl_attribute string) error {
+	description, err := b.DescribeTableContext(ctx, table_name)
+	if err != nil {
+		return err
+	}
+	if description.TTLAttribute == ttl_attribute {
+		b.definitions.defineTTLAttribute(table_name, ttl_attribute)
+		return nil
+	}
+	return b.withCollection(ctx, table_name, func(c *mgo.Collection) error {
+		if len(description.TTLAttribute) > 0 {
+			err := c.DropIndexName(ttlIndexNameMongoDB(description.TTLAttribute))
+			if err != nil {
+				return err
+			}
+			b.definitions.defineTTLAttribute(table_name, "")
+		}
+		if len(ttl_attribute) > 0 {
+			err := ensureTTLIndexMongoDB(c, ttl_attribute)
+			if err != nil {
+				return err
+			}
+			b.definitions.defineTTLAttribute(table_name, ttl_attribute)
+		}
+		return nil
+	})
+}
+
+// find queries the items of a collection that match q and have not expired.  MongoDB
+// removes expired items in the background, so it can return them until then.
+func (b *BackendMongoDB) find(c *mgo.Collection, q interface{}) (*mgo.Query, error) {
+	ttl_attribute := b.ttlAttribute(c)
+	if len(ttl_attribute) == 0 {
+		return c.Find(q), nil
+	}
+	unexpired := unexpiredMongoDB(ttl_attribute, time.Now())
+	if q == nil {
+		return c.Find(unexpired), nil
+	}
+	return c.Find(bson.M{"$and": []interface{}{q, unexpired}}), nil
+}
+
+// ttlAttribute returns the time to live attribute of a collection.  The TTL index of a
+// collection that is not defined is read, so that expired items are hidden without
+// DefineTables.  If the collection has none, or if its indexes cannot be listed, it is
+// read as having none until the indexes are listed again after ttlLookupInterval.
+func (b *BackendMongoDB) ttlAttribute(c *mgo.Collection) string {
+	now := time.Now()
+	if !b.definitions.lookupTTLAttribute(c.Name, now) {
+		return b.definitions.ttlAttribute(c.Name)
+	}
+	indexes, err := c.Indexes()
+	if err != nil {
+		b.definitions.missTTLAttribute(c.Name, now)
+		return ""
+	}
+	for _, x := range indexes {
+		if len(x.Key) == 1 && x.Name == ttlIndexNameMongoDB(x.Key[0]) {
+			b.definitions.defineTTLAttribute(c.Name, x.Key[0])
+			return x.Key[0]
+		}
+	}
+	b.definitions.missTTLAttribute(c.Name, now)
+	return ""
+}
+
+// unexpiredMongoDB returns a query for the items that have not expired at the time.
+// Items expire when the attribute is a date that has passed.
+func unexpiredMongoDB(ttl_attribute string, now time.Time) bson.M {
+	return bson.M{ttl_attribute: bson.M{"$not": bson.M{"$lte": now}}}
+}
+
+// ensureTTLIndexMongoDB creates a TTL index that removes items once the time in the
+// attribute has passed.  mgo cannot create indexes that expire after 0 seconds, so the
+// index is created with a command.
+func ensureTTLIndexMongoDB(c *mgo.Collection, ttl_attribute string) error {
+	return c.Database.Run(bson.D{
+		{Name: "createIndexes", Value: c.Name},
+		{Name: "indexes", Value: []bson.M{{
+			"key":                bson.D{{Name: ttl_attribute, Value: 1}},
+			"name":               ttlIndexNameMongoDB(ttl_attribute),
+			"expireAfterSeconds": 0,
+		}}},
+	}, nil)
+}
+
+// ttlIndexNameMongoDB returns the name of the TTL index on an attribute.
+func ttlIndexNameMongoDB(ttl_attribute string) string {
+	return ttl_attribute + "-ttl"
+}
+
+// keyMongoDB returns the key of an index on the hash key and on the range key, if its
+// name is not empty.
+func keyMongoDB(hash_key string, range_key string) []string {
+	key := []string{keyNameMongoDB(hash_key)}
+	if len(range_key) > 0 {
+		key = append(key, keyNameMongoDB(range_key))
+	}
+	return key
+}
+
+// keyNameMongoDB returns the name of a key attribute in MongoDB, where "id" is stored
+// as "_id".
+func keyNameMongoDB(attribute_name string) string {
+	if attribute_name == "id" {
+		return "_id"
+	}
+	return attribute_name
+}
+
 func (b *BackendMongoDB) DeleteTables(table_names []string) error {
+	return b.DeleteTablesContext(context.Background(), table_names)
+}
+
+func (b *BackendMongoDB) DeleteTablesContext(ctx context.Context, table_names []string) error {
 	for _, table_name := range table_names {
-		b.DeleteTable(table_name)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := b.DeleteTableContext(ctx, table_name)
+		if err != nil && !errors.Is(err, ErrTableNotFound) {
+			return err
+		}
 	}
 	return nil
 }
 
 func (b *BackendMongoDB) DeleteTable(table_name string) error {
-	c := b.GetCollection(table_name)
-	err := c.DropCollection()
+	return b.DeleteTableContext(context.Background(), table_name)
+}
+
+func (b *BackendMongoDB) DeleteTableContext(ctx context.Context, table_name string) error {
+	return b.withCollection(ctx, table_name, func(c *mgo.Collection) error {
+		return c.DropCollection()
+	})
+}
+
+func (b *BackendMongoDB) ListTables() ([]string, error) {
+	return b.ListTablesContext(context.Background())
+}
+
+// ListTablesContext returns the names of the collections of the database, except for
+// the system collections.
+func (b *BackendMongoDB) ListTablesContext(ctx context.Context) ([]string, error) {
+	table_names := []string{}
+	err := b.withDatabase(ctx, func(db *mgo.Database) error {
+		names, err := db.CollectionNames()
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if !strings.HasPrefix(name, "system.") {
+				table_names = append(table_names, name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return table_names, nil
+}
+
+func (b *BackendMongoDB) DescribeTable(table_name string) (*TableDescription, error) {
+	return b.DescribeTableContext(context.Background(), table_name)
+}
+
+// DescribeTableContext returns the indexes of a collection and its statistics.
+// MongoDB does not store key schemas, so the key is the key of the definition of the
+// table, and the unique index on a composite key and the TTL index are not secondary
+// indexes.
+// Collections have no throughput and are always active.
+func (b *BackendMongoDB) DescribeTableContext(ctx context.Context, table_name string) (*TableDescription, error) {
+	partition_key, sort_key := b.definitions.keySchema(table_name)
+	return b.describeTable(ctx, table_name, partition_key, sort_key)
+}
+
+func (b *BackendMongoDB) describeTable(ctx context.Context, table_name string, partition_key Attribute, sort_key Attribute) (*TableDescription, error) {
+	exists, err := b.TableExistsContext(ctx, table_name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, &Error{Kind: ErrTableNotFound, Err: fmt.Errorf("table %q does not exist", table_name)}
+	}
+
+	key := keyMongoDB(partition_key.Name, sort_key.Name)
+	description := &TableDescription{
+		Table: Table{
+			Name:             table_name,
+			PartitionKey:     partition_key,
+			SortKey:          sort_key,
+			SecondaryIndexes: []Index{},
+		},
+		Status: TableStatusActive,
+	}
+	err = b.withCollection(ctx, table_name, func(c *mgo.Collection) error {
+		indexes, err := c.Indexes()
+		if err != nil {
+			return err
+		}
+		for _, x := range indexes {
+			if x.Name == "_id_" || (x.Unique && reflect.DeepEqual(x.Key, key)) {
+				continue
+			}
+			if len(x.Key) == 1 && x.Name == ttlIndexNameMongoDB(x.Key[0]) {
+				description.TTLAttribute = x.Key[0]
+				continue
+			}
+			index := Index{
+				Name:        x.Name,
+				HashKey:     attributeNameMongoDB(x.Key[0]),
+				Projection:  ProjectionAll,
+				Unique:      x.Unique,
+				Sparse:      x.Sparse,
+				ExpireAfter: x.ExpireAfter,
+			}
+			if len(x.Key) > 1 {
+				index.RangeKey = attributeNameMongoDB(x.Key[1])
+			}
+			description.SecondaryIndexes = append(description.SecondaryIndexes, index)
+		}
+		stats := struct {
+			Count int64 `bson:"count"`
+			Size  int64 `bson:"size"`
+		}{}
+		err = c.Database.Run(bson.D{{Name: "collStats", Value: table_name}}, &stats)
+		if err != nil {
+			return err
+		}
+		description.ItemCount = stats.Count
+		description.SizeBytes = stats.Size
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return description, nil
+}
+
+// attributeNameMongoDB is the inverse of keyNameMongoDB.
+func attributeNameMongoDB(key_name string) string {
+	if key_name == "_id" {
+		return "id"
+	}
+	return key_name
+}
+
+func (b *BackendMongoDB) TableExists(table_name string) (bool, error) {
+	return b.TableExistsContext(context.Background(), table_name)
+}
+
+func (b *BackendMongoDB) TableExistsContext(ctx context.Context, table_name string) (bool, error) {
+	table_names, err := b.ListTablesContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, name := range table_names {
+		if name == table_name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// cursorMongoDB is the state of a continuation token for MongoDB.
+type cursorMongoDB struct {
+	Skip int
+}
+
+// wrapMongoDBError maps the errors returned by mgo to the portable errors of this
+// package.  The original error remains available through errors.As.
+func wrapMongoDBError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if err == mgo.ErrNotFound {
+		return &Error{Kind: ErrNotFound, Err: err}
+	}
+	if mgo.IsDup(err) {
+		return &Error{Kind: ErrConflict, Err: err}
+	}
+	code := 0
+	message := ""
+	switch e := err.(type) {
+	case *mgo.QueryError:
+		code = e.Code
+		message = e.Message
+	case *mgo.LastError:
+		code = e.Code
+		message = e.Err
+	}
+	switch {
+	case code == 26 || strings.Contains(message, "ns not found"):
+		return &Error{Kind: ErrTableNotFound, Err: err}
+	case code == 48:
+		return &Error{Kind: ErrTableExists, Err: err}
+	case code == 16500:
+		return &Error{Kind: ErrThrottled, Err: err}
+	case code == 2 || code == 9:
+		return &Error{Kind: ErrInvalidQuery, Err: err}
+	}
 	return err
 }
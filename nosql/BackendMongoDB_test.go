@@ -0,0 +1,27 @@
+package nosql_test
+
+import (
+	"os"
+	"testing"
+)
+
+import (
+	"github.com/spatialcurrent/go-nosql/nosql"
+	"github.com/spatialcurrent/go-nosql/nosql/nosqltest"
+)
+
+// TestBackendMongoDB runs the conformance suite against the database in NOSQL_MONGODB_DSN,
+// for example "mongodb://localhost/nosqltest".
+func TestBackendMongoDB(t *testing.T) {
+	dsn := os.Getenv("NOSQL_MONGODB_DSN")
+	if len(dsn) == 0 {
+		t.Skip("NOSQL_MONGODB_DSN is not set")
+	}
+	nosqltest.RunConformance(t, func() nosql.Backend {
+		b, err := nosql.Open(dsn)
+		if err != nil {
+			t.Fatalf("Open returned error: %v", err)
+		}
+		return b
+	})
+}
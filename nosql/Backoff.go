@@ -0,0 +1,17 @@
+package nosql
+
+import (
+	"time"
+)
+
+// maxRetries is the number of times partially processed batch requests are retried.
+const maxRetries = 10
+
+// backoff returns the delay before the given retry, which doubles from 50
+// milliseconds up to 5 seconds.
+func backoff(attempt int) time.Duration {
+	if attempt >= 7 {
+		return 5 * time.Second
+	}
+	return 50 * time.Millisecond << uint(attempt)
+}
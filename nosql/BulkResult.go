@@ -0,0 +1,45 @@
+package nosql
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// BulkResult is the outcome of writing one item of a bulk write.  Index is the
+// position of the item in the input, Id is its id if the table is keyed by id, and Err
+// is nil if the item was written.
+type BulkResult struct {
+	Index int
+	Id    string
+	Err   error
+}
+
+// bulkError returns a BulkError for the failed results, or nil if there are none.
+func bulkError(results []BulkResult) error {
+	failed := make([]BulkResult, 0)
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return &BulkError{Failed: failed}
+}
+
+// sliceItems returns the elements of a slice, or of a pointer to a slice, of items.
+func sliceItems(items interface{}) ([]interface{}, error) {
+	v := reflect.ValueOf(items)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("items must be a slice, not %T", items)}
+	}
+	values := make([]interface{}, v.Len())
+	for i := range values {
+		values[i] = v.Index(i).Interface()
+	}
+	return values, nil
+}
@@ -1,21 +1,20 @@
 package nosql
 
+import (
+	"fmt"
+	"strings"
+)
+
 func ConnectToBackend(backend_name string, options map[string]string) (*Backend, error) {
 
-	var backend Backend
+	factory, ok := getFactory(backend_name)
+	if !ok {
+		return nil, fmt.Errorf("%w %q, expecting one of %s", ErrUnknownBackend, backend_name, strings.Join(Backends(), ", "))
+	}
 
-	if backend_name == "dynamodb" {
-		backend = &BackendDynamoDB{}
-		err := backend.Connect(options)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		backend = &BackendMongoDB{}
-		err := backend.Connect(options)
-		if err != nil {
-			return nil, err
-		}
+	backend, err := factory(options)
+	if err != nil {
+		return nil, err
 	}
 
 	return &backend, nil
@@ -0,0 +1,29 @@
+package nosql
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+// encodeCursor serializes the backend-specific state of a page into an opaque
+// continuation token.
+func encodeCursor(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeCursor parses a continuation token created by encodeCursor.
+func decodeCursor(token string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return &Error{Kind: ErrInvalidQuery, Err: err}
+	}
+	err = json.Unmarshal(b, v)
+	if err != nil {
+		return &Error{Kind: ErrInvalidQuery, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package nosql
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrNotFound       = errors.New("item not found")
+	ErrConflict       = errors.New("conflict")
+	ErrTableNotFound  = errors.New("table not found")
+	ErrTableExists    = errors.New("table already exists")
+	ErrThrottled      = errors.New("request throttled")
+	ErrInvalidQuery   = errors.New("invalid query")
+	ErrUnknownBackend = errors.New("unknown backend")
+	ErrInvalidConfig  = errors.New("invalid configuration")
+	ErrNotSupported   = errors.New("not supported")
+)
+
+// Error wraps a native error returned by a backend with one of the portable errors
+// above, so that both errors.Is(err, ErrNotFound) and inspection of the original
+// error work.
+type Error struct {
+	Kind error
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Kind.Error()
+	}
+	return e.Kind.Error() + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// MissingItemsError is returned when some of the items requested by id do not exist.
+// The items that exist are still returned.
+type MissingItemsError struct {
+	Ids []string
+}
+
+func (e *MissingItemsError) Error() string {
+	return fmt.Sprintf("%d items not found: %s", len(e.Ids), strings.Join(e.Ids, ", "))
+}
+
+func (e *MissingItemsError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// BulkError is returned by bulk writes when some of the items failed.  It matches any
+// of the errors of the failed items with errors.Is.
+type BulkError struct {
+	Failed []BulkResult
+}
+
+func (e *BulkError) Error() string {
+	if len(e.Failed) == 1 {
+		return fmt.Sprintf("item %d failed: %v", e.Failed[0].Index, e.Failed[0].Err)
+	}
+	return fmt.Sprintf("%d items failed, including item %d: %v", len(e.Failed), e.Failed[0].Index, e.Failed[0].Err)
+}
+
+func (e *BulkError) Is(target error) bool {
+	for _, r := range e.Failed {
+		if errors.Is(r.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// VersionConflictError is returned by writes to versioned tables when the stored
+// version of the item is not the given version, because the item changed since it
+// was read.  Read the item again and retry.  It matches ErrConflict.
+type VersionConflictError struct {
+	Table   string
+	Id      string
+	Version int64
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("item %q of table %q is not at version %d", e.Id, e.Table, e.Version)
+}
+
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// TransactionError is returned when a transaction is canceled because some of its
+// operations failed, in which case none of the operations were applied.  Index is the
+// position of the operation in the transaction.  It matches any of the errors of the
+// failed operations with errors.Is.
+type TransactionError struct {
+	Failed []BulkResult
+}
+
+func (e *TransactionError) Error() string {
+	if len(e.Failed) == 0 {
+		return "transaction canceled"
+	}
+	return fmt.Sprintf("transaction canceled, operation %d on item %q failed: %v", e.Failed[0].Index, e.Failed[0].Id, e.Failed[0].Err)
+}
+
+func (e *TransactionError) Is(target error) bool {
+	for _, r := range e.Failed {
+		if errors.Is(r.Err, target) {
+			return true
+		}
+	}
+	return false
+}
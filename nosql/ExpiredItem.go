@@ -0,0 +1,124 @@
+package nosql
+
+import (
+	"strconv"
+	"time"
+)
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// expiredItem returns true if the time to live attribute of an item is a Unix time in
+// seconds that has passed.  Items without the attribute do not expire.
+func expiredItem(item map[string]*dynamodb.AttributeValue, ttl_attribute string, now time.Time) bool {
+	if len(ttl_attribute) == 0 {
+		return false
+	}
+	av, ok := item[ttl_attribute]
+	if !ok || av == nil || av.N == nil {
+		return false
+	}
+	expires, err := strconv.ParseFloat(*av.N, 64)
+	if err != nil {
+		return false
+	}
+	return expires <= float64(now.Unix())
+}
+
+// unexpiredFilter returns a filter for the items that have not expired at the time, for
+// reads that cannot filter items after they are read.
+func unexpiredFilter(ttl_attribute string, now time.Time) Filter {
+	return Or(Not(Exists(ttl_attribute)), Gt(ttl_attribute, now.Unix()))
+}
+
+// unexpiredItems returns the items that have not expired, ready to be unmarshaled.
+func unexpiredItems(items []map[string]*dynamodb.AttributeValue, ttl_attribute string) []map[string]*dynamodb.AttributeValue {
+	if len(ttl_attribute) == 0 {
+		return items
+	}
+	now := time.Now()
+	unexpired := make([]map[string]*dynamodb.AttributeValue, 0, len(items))
+	for _, item := range items {
+		if !expiredItem(item, ttl_attribute, now) {
+			unexpired = append(unexpired, unmarshalExpiry(item, ttl_attribute))
+		}
+	}
+	return unexpired
+}
+
+// marshalExpiry stores the time to live attribute of a marshaled item, which is a
+// time.Time marshaled as an RFC 3339 string, as a Unix time in seconds, since DynamoDB
+// only expires numbers.  Other values are left as they are.
+func marshalExpiry(item map[string]*dynamodb.AttributeValue, ttl_attribute string) {
+	if len(ttl_attribute) == 0 {
+		return
+	}
+	av, ok := item[ttl_attribute]
+	if !ok || av == nil || av.S == nil {
+		return
+	}
+	t, err := time.Parse(time.RFC3339, *av.S)
+	if err != nil {
+		return
+	}
+	item[ttl_attribute] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(t.Unix(), 10))}
+}
+
+// unmarshalExpiry returns a copy of an item with the Unix time of the time to live
+// attribute as an RFC 3339 string, which unmarshals into a time.Time.
+func unmarshalExpiry(item map[string]*dynamodb.AttributeValue, ttl_attribute string) map[string]*dynamodb.AttributeValue {
+	if len(ttl_attribute) == 0 {
+		return item
+	}
+	av, ok := item[ttl_attribute]
+	if !ok || av == nil || av.N == nil {
+		return item
+	}
+	expires, err := strconv.ParseFloat(*av.N, 64)
+	if err != nil {
+		return item
+	}
+	copied := make(map[string]*dynamodb.AttributeValue, len(item))
+	for k, v := range item {
+		copied[k] = v
+	}
+	copied[ttl_attribute] = &dynamodb.AttributeValue{S: aws.String(time.Unix(int64(expires), 0).UTC().Format(time.RFC3339))}
+	return copied
+}
+
+// unmarshalExpiryItems returns the items like unmarshalExpiry.
+func unmarshalExpiryItems(items []map[string]*dynamodb.AttributeValue, ttl_attribute string) []map[string]*dynamodb.AttributeValue {
+	if len(ttl_attribute) == 0 {
+		return items
+	}
+	unmarshaled := make([]map[string]*dynamodb.AttributeValue, len(items))
+	for i, item := range items {
+		unmarshaled[i] = unmarshalExpiry(item, ttl_attribute)
+	}
+	return unmarshaled
+}
+
+// marshalExpiryOperations returns the operations of an update with the times set to
+// the time to live attribute as Unix times in seconds, like marshalExpiry.
+func marshalExpiryOperations(operations []UpdateOperation, ttl_attribute string) []UpdateOperation {
+	if len(ttl_attribute) == 0 {
+		return operations
+	}
+	marshaled := make([]UpdateOperation, len(operations))
+	for i, op := range operations {
+		if op.Attribute == ttl_attribute {
+			switch t := op.Value.(type) {
+			case time.Time:
+				op.Value = t.Unix()
+			case *time.Time:
+				if t != nil {
+					op.Value = t.Unix()
+				}
+			}
+		}
+		marshaled[i] = op
+	}
+	return marshaled
+}
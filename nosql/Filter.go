@@ -0,0 +1,144 @@
+package nosql
+
+// Filter is a backend-neutral condition on the attributes of an item.  Filters are
+// built with Eq, Ne, Lt, Lte, Gt, Gte, In, Between, BeginsWith, Contains, Exists, And,
+// Or, and Not, and are compiled to a query document for MongoDB and to condition
+// expressions for DynamoDB.  Attribute names may use dots to refer to nested
+// attributes.
+type Filter interface {
+	filter()
+}
+
+type Operator string
+
+const (
+	OperatorEq         Operator = "="
+	OperatorNe         Operator = "<>"
+	OperatorLt         Operator = "<"
+	OperatorLte        Operator = "<="
+	OperatorGt         Operator = ">"
+	OperatorGte        Operator = ">="
+	OperatorBeginsWith Operator = "begins_with"
+	OperatorContains   Operator = "contains"
+)
+
+// FilterComparison compares an attribute with a value.
+type FilterComparison struct {
+	Attribute string
+	Operator  Operator
+	Value     interface{}
+}
+
+// FilterIn matches items where the attribute equals any of the values.
+type FilterIn struct {
+	Attribute string
+	Values    []interface{}
+}
+
+// FilterBetween matches items where the attribute is within [Lower, Upper].
+type FilterBetween struct {
+	Attribute string
+	Lower     interface{}
+	Upper     interface{}
+}
+
+// FilterExists matches items where the attribute is present.
+type FilterExists struct {
+	Attribute string
+}
+
+// FilterAnd matches items that match all of the filters.
+type FilterAnd struct {
+	Filters []Filter
+}
+
+// FilterOr matches items that match any of the filters.
+type FilterOr struct {
+	Filters []Filter
+}
+
+// FilterNot matches items that do not match the filter.
+type FilterNot struct {
+	Filter Filter
+}
+
+func (f *FilterComparison) filter() {}
+func (f *FilterIn) filter()         {}
+func (f *FilterBetween) filter()    {}
+func (f *FilterExists) filter()     {}
+func (f *FilterAnd) filter()        {}
+func (f *FilterOr) filter()         {}
+func (f *FilterNot) filter()        {}
+
+func Eq(attribute_name string, value interface{}) Filter {
+	return &FilterComparison{Attribute: attribute_name, Operator: OperatorEq, Value: value}
+}
+
+func Ne(attribute_name string, value interface{}) Filter {
+	return &FilterComparison{Attribute: attribute_name, Operator: OperatorNe, Value: value}
+}
+
+func Lt(attribute_name string, value interface{}) Filter {
+	return &FilterComparison{Attribute: attribute_name, Operator: OperatorLt, Value: value}
+}
+
+func Lte(attribute_name string, value interface{}) Filter {
+	return &FilterComparison{Attribute: attribute_name, Operator: OperatorLte, Value: value}
+}
+
+func Gt(attribute_name string, value interface{}) Filter {
+	return &FilterComparison{Attribute: attribute_name, Operator: OperatorGt, Value: value}
+}
+
+func Gte(attribute_name string, value interface{}) Filter {
+	return &FilterComparison{Attribute: attribute_name, Operator: OperatorGte, Value: value}
+}
+
+func BeginsWith(attribute_name string, prefix string) Filter {
+	return &FilterComparison{Attribute: attribute_name, Operator: OperatorBeginsWith, Value: prefix}
+}
+
+// Contains matches items where a string attribute contains the value as a substring,
+// or where a list or set attribute contains the value as an element.
+func Contains(attribute_name string, value interface{}) Filter {
+	return &FilterComparison{Attribute: attribute_name, Operator: OperatorContains, Value: value}
+}
+
+func In(attribute_name string, values ...interface{}) Filter {
+	return &FilterIn{Attribute: attribute_name, Values: values}
+}
+
+func Between(attribute_name string, lower interface{}, upper interface{}) Filter {
+	return &FilterBetween{Attribute: attribute_name, Lower: lower, Upper: upper}
+}
+
+func Exists(attribute_name string) Filter {
+	return &FilterExists{Attribute: attribute_name}
+}
+
+func And(filters ...Filter) Filter {
+	return &FilterAnd{Filters: filters}
+}
+
+func Or(filters ...Filter) Filter {
+	return &FilterOr{Filters: filters}
+}
+
+func Not(f Filter) Filter {
+	return &FilterNot{Filter: f}
+}
+
+// conjuncts returns the filters that must all match for f to match.
+func conjuncts(f Filter) []Filter {
+	if f == nil {
+		return []Filter{}
+	}
+	if and, ok := f.(*FilterAnd); ok {
+		filters := []Filter{}
+		for _, x := range and.Filters {
+			filters = append(filters, conjuncts(x)...)
+		}
+		return filters
+	}
+	return []Filter{f}
+}
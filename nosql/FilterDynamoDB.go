@@ -0,0 +1,159 @@
+package nosql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// expressionDynamoDB collects the placeholder names and values of DynamoDB condition,
+// filter, and update expressions.
+type expressionDynamoDB struct {
+	names  map[string]*string
+	values map[string]*dynamodb.AttributeValue
+	paths  map[string]string
+}
+
+func newExpressionDynamoDB() *expressionDynamoDB {
+	return &expressionDynamoDB{
+		names:  map[string]*string{},
+		values: map[string]*dynamodb.AttributeValue{},
+		paths:  map[string]string{},
+	}
+}
+
+// name returns the placeholder for an attribute name.  Dots separate the names of
+// nested attributes.
+func (e *expressionDynamoDB) name(attribute_name string) string {
+	parts := strings.Split(attribute_name, ".")
+	for i, part := range parts {
+		placeholder, ok := e.paths[part]
+		if !ok {
+			placeholder = "#n" + strconv.Itoa(len(e.paths))
+			e.paths[part] = placeholder
+			e.names[placeholder] = aws.String(part)
+		}
+		parts[i] = placeholder
+	}
+	return strings.Join(parts, ".")
+}
+
+// value returns the placeholder for a value marshaled as a DynamoDB attribute.
+func (e *expressionDynamoDB) value(v interface{}) (string, error) {
+	av, err := dynamodbattribute.Marshal(v)
+	if err != nil {
+		return "", &Error{Kind: ErrInvalidQuery, Err: err}
+	}
+	return e.attributeValue(av), nil
+}
+
+// attributeValue returns the placeholder for an attribute value.
+func (e *expressionDynamoDB) attributeValue(av *dynamodb.AttributeValue) string {
+	placeholder := ":v" + strconv.Itoa(len(e.values))
+	e.values[placeholder] = av
+	return placeholder
+}
+
+// expressionAttributeNames returns the names for the input of a request, or nil if
+// there are none, since DynamoDB rejects empty maps.
+func (e *expressionDynamoDB) expressionAttributeNames() map[string]*string {
+	if len(e.names) == 0 {
+		return nil
+	}
+	return e.names
+}
+
+// expressionAttributeValues returns the values for the input of a request, or nil if
+// there are none, since DynamoDB rejects empty maps.
+func (e *expressionDynamoDB) expressionAttributeValues() map[string]*dynamodb.AttributeValue {
+	if len(e.values) == 0 {
+		return nil
+	}
+	return e.values
+}
+
+// filter compiles a filter into a DynamoDB condition expression.
+func (e *expressionDynamoDB) filter(f Filter) (string, error) {
+	switch f := f.(type) {
+	case *FilterComparison:
+		n := e.name(f.Attribute)
+		v, err := e.value(f.Value)
+		if err != nil {
+			return "", err
+		}
+		switch f.Operator {
+		case OperatorEq, OperatorNe, OperatorLt, OperatorLte, OperatorGt, OperatorGte:
+			return n + " " + string(f.Operator) + " " + v, nil
+		case OperatorBeginsWith:
+			if _, ok := f.Value.(string); !ok {
+				return "", &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("begins_with on %q requires a string value", f.Attribute)}
+			}
+			return "begins_with(" + n + ", " + v + ")", nil
+		case OperatorContains:
+			return "contains(" + n + ", " + v + ")", nil
+		}
+		return "", &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("unknown operator %q", f.Operator)}
+	case *FilterIn:
+		if len(f.Values) == 0 {
+			return "", &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("in on %q requires at least one value", f.Attribute)}
+		}
+		n := e.name(f.Attribute)
+		values := make([]string, 0, len(f.Values))
+		for _, x := range f.Values {
+			v, err := e.value(x)
+			if err != nil {
+				return "", err
+			}
+			values = append(values, v)
+		}
+		return n + " IN (" + strings.Join(values, ", ") + ")", nil
+	case *FilterBetween:
+		n := e.name(f.Attribute)
+		lower, err := e.value(f.Lower)
+		if err != nil {
+			return "", err
+		}
+		upper, err := e.value(f.Upper)
+		if err != nil {
+			return "", err
+		}
+		return n + " BETWEEN " + lower + " AND " + upper, nil
+	case *FilterExists:
+		return "attribute_exists(" + e.name(f.Attribute) + ")", nil
+	case *FilterAnd:
+		if len(f.Filters) == 0 {
+			return "", &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("and requires at least one filter")}
+		}
+		return e.filters(f.Filters, " AND ")
+	case *FilterOr:
+		if len(f.Filters) == 0 {
+			return "", &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("or requires at least one filter")}
+		}
+		return e.filters(f.Filters, " OR ")
+	case *FilterNot:
+		x, err := e.filter(f.Filter)
+		if err != nil {
+			return "", err
+		}
+		return "NOT (" + x + ")", nil
+	}
+	return "", &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("unknown filter %T", f)}
+}
+
+func (e *expressionDynamoDB) filters(filters []Filter, sep string) (string, error) {
+	parts := make([]string, 0, len(filters))
+	for _, f := range filters {
+		x, err := e.filter(f)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, "("+x+")")
+	}
+	return strings.Join(parts, sep), nil
+}
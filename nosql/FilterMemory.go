@@ -0,0 +1,165 @@
+package nosql
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+import (
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// matchFilterMemory evaluates a filter against an item with the semantics of DynamoDB
+// condition expressions: comparisons with a missing attribute, or with a value of a
+// different type, are false.  A nil filter matches every item.
+func matchFilterMemory(item map[string]*dynamodb.AttributeValue, f Filter) (bool, error) {
+	if f == nil {
+		return true, nil
+	}
+	switch f := f.(type) {
+	case *FilterComparison:
+		av := attributeValueAtPath(item, f.Attribute)
+		v, err := marshalFilterValue(f.Value)
+		if err != nil {
+			return false, err
+		}
+		if av == nil {
+			return false, nil
+		}
+		switch f.Operator {
+		case OperatorEq:
+			return equalAttributeValues(av, v), nil
+		case OperatorNe:
+			return !equalAttributeValues(av, v), nil
+		case OperatorLt, OperatorLte, OperatorGt, OperatorGte:
+			if !isScalarAttributeValue(av) || attributeValueRank(av) != attributeValueRank(v) {
+				return false, nil
+			}
+			c := compareAttributeValues(av, v)
+			switch f.Operator {
+			case OperatorLt:
+				return c < 0, nil
+			case OperatorLte:
+				return c <= 0, nil
+			case OperatorGt:
+				return c > 0, nil
+			}
+			return c >= 0, nil
+		case OperatorBeginsWith:
+			if v.S == nil {
+				return false, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("begins_with on %q requires a string value", f.Attribute)}
+			}
+			return av.S != nil && strings.HasPrefix(*av.S, *v.S), nil
+		case OperatorContains:
+			return containsAttributeValue(av, v), nil
+		}
+		return false, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("unknown operator %q", f.Operator)}
+	case *FilterIn:
+		if len(f.Values) == 0 {
+			return false, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("in on %q requires at least one value", f.Attribute)}
+		}
+		av := attributeValueAtPath(item, f.Attribute)
+		for _, x := range f.Values {
+			v, err := marshalFilterValue(x)
+			if err != nil {
+				return false, err
+			}
+			if av != nil && equalAttributeValues(av, v) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case *FilterBetween:
+		av := attributeValueAtPath(item, f.Attribute)
+		lower, err := marshalFilterValue(f.Lower)
+		if err != nil {
+			return false, err
+		}
+		upper, err := marshalFilterValue(f.Upper)
+		if err != nil {
+			return false, err
+		}
+		if av == nil || !isScalarAttributeValue(av) || attributeValueRank(av) != attributeValueRank(lower) || attributeValueRank(av) != attributeValueRank(upper) {
+			return false, nil
+		}
+		return compareAttributeValues(lower, av) <= 0 && compareAttributeValues(av, upper) <= 0, nil
+	case *FilterExists:
+		return attributeValueAtPath(item, f.Attribute) != nil, nil
+	case *FilterAnd:
+		if len(f.Filters) == 0 {
+			return false, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("and requires at least one filter")}
+		}
+		for _, x := range f.Filters {
+			ok, err := matchFilterMemory(item, x)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	case *FilterOr:
+		if len(f.Filters) == 0 {
+			return false, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("or requires at least one filter")}
+		}
+		for _, x := range f.Filters {
+			ok, err := matchFilterMemory(item, x)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	case *FilterNot:
+		ok, err := matchFilterMemory(item, f.Filter)
+		return !ok, err
+	}
+	return false, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("unknown filter %T", f)}
+}
+
+func marshalFilterValue(v interface{}) (*dynamodb.AttributeValue, error) {
+	av, err := dynamodbattribute.Marshal(v)
+	if err != nil {
+		return nil, &Error{Kind: ErrInvalidQuery, Err: err}
+	}
+	return av, nil
+}
+
+func isScalarAttributeValue(av *dynamodb.AttributeValue) bool {
+	return av.N != nil || av.S != nil || av.B != nil
+}
+
+// containsAttributeValue returns true if the string av contains the string v, or if
+// the set or list av contains the element v.
+func containsAttributeValue(av *dynamodb.AttributeValue, v *dynamodb.AttributeValue) bool {
+	switch {
+	case av.S != nil:
+		return v.S != nil && strings.Contains(*av.S, *v.S)
+	case av.B != nil:
+		return v.B != nil && bytes.Contains(av.B, v.B)
+	case av.SS != nil:
+		for _, x := range av.SS {
+			if v.S != nil && *x == *v.S {
+				return true
+			}
+		}
+	case av.NS != nil:
+		for _, x := range av.NS {
+			if v.N != nil && compareNumbers(*x, *v.N) == 0 {
+				return true
+			}
+		}
+	case av.BS != nil:
+		for _, x := range av.BS {
+			if v.B != nil && bytes.Equal(x, v.B) {
+				return true
+			}
+		}
+	case av.L != nil:
+		for _, x := range av.L {
+			if equalAttributeValues(x, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
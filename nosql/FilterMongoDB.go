@@ -0,0 +1,98 @@
+package nosql
+
+import (
+	"fmt"
+	"regexp"
+)
+
+import (
+	"gopkg.in/mgo.v2/bson"
+)
+
+// compileFilterMongoDB compiles a filter into a MongoDB query document, where "id" is
+// matched against "_id".  A nil filter matches every document.
+func compileFilterMongoDB(f Filter) (bson.M, error) {
+	if f == nil {
+		return bson.M{}, nil
+	}
+	switch f := f.(type) {
+	case *FilterComparison:
+		k := keyNameMongoDB(f.Attribute)
+		switch f.Operator {
+		case OperatorEq:
+			return bson.M{k: f.Value}, nil
+		case OperatorNe:
+			return bson.M{k: bson.M{"$ne": f.Value}}, nil
+		case OperatorLt:
+			return bson.M{k: bson.M{"$lt": f.Value}}, nil
+		case OperatorLte:
+			return bson.M{k: bson.M{"$lte": f.Value}}, nil
+		case OperatorGt:
+			return bson.M{k: bson.M{"$gt": f.Value}}, nil
+		case OperatorGte:
+			return bson.M{k: bson.M{"$gte": f.Value}}, nil
+		case OperatorBeginsWith:
+			s, ok := f.Value.(string)
+			if !ok {
+				return nil, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("begins_with on %q requires a string value", f.Attribute)}
+			}
+			return bson.M{k: bson.M{"$regex": "^" + regexp.QuoteMeta(s)}}, nil
+		case OperatorContains:
+			if s, ok := f.Value.(string); ok {
+				// A string matches both substrings of a string attribute and elements of an array.
+				return bson.M{"$or": []bson.M{
+					bson.M{k: bson.M{"$regex": regexp.QuoteMeta(s)}},
+					bson.M{k: s},
+				}}, nil
+			}
+			return bson.M{k: f.Value}, nil
+		}
+		return nil, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("unknown operator %q", f.Operator)}
+	case *FilterIn:
+		if len(f.Values) == 0 {
+			return nil, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("in on %q requires at least one value", f.Attribute)}
+		}
+		return bson.M{keyNameMongoDB(f.Attribute): bson.M{"$in": f.Values}}, nil
+	case *FilterBetween:
+		return bson.M{keyNameMongoDB(f.Attribute): bson.M{"$gte": f.Lower, "$lte": f.Upper}}, nil
+	case *FilterExists:
+		return bson.M{keyNameMongoDB(f.Attribute): bson.M{"$exists": true}}, nil
+	case *FilterAnd:
+		if len(f.Filters) == 0 {
+			return nil, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("and requires at least one filter")}
+		}
+		q, err := compileFiltersMongoDB(f.Filters)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{"$and": q}, nil
+	case *FilterOr:
+		if len(f.Filters) == 0 {
+			return nil, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("or requires at least one filter")}
+		}
+		q, err := compileFiltersMongoDB(f.Filters)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{"$or": q}, nil
+	case *FilterNot:
+		q, err := compileFilterMongoDB(f.Filter)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{"$nor": []bson.M{q}}, nil
+	}
+	return nil, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("unknown filter %T", f)}
+}
+
+func compileFiltersMongoDB(filters []Filter) ([]bson.M, error) {
+	q := make([]bson.M, 0, len(filters))
+	for _, f := range filters {
+		x, err := compileFilterMongoDB(f)
+		if err != nil {
+			return nil, err
+		}
+		q = append(q, x)
+	}
+	return q, nil
+}
@@ -0,0 +1,47 @@
+package nosql_test
+
+import (
+	"testing"
+)
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+
+	"github.com/spatialcurrent/go-nosql/nosql"
+	"github.com/spatialcurrent/go-nosql/nosql/parser"
+)
+
+// FuzzParsedFilter checks that every expression the parser accepts compiles for
+// DynamoDB and MongoDB and can be matched by the memory backend.
+func FuzzParsedFilter(f *testing.F) {
+	for _, s := range []string{
+		`status = "active" AND created > "2024-01-01"`,
+		`(age >= 21 OR guardian EXISTS) AND NOT role IN ("admin", 'owner')`,
+		`name begins_with "Jo" and score between 10 and 20.5 and tags contains "go"`,
+		`a.b NOT BETWEEN -1 AND 1e3 OR c = null OR d != true`,
+	} {
+		f.Add(s)
+	}
+	item := map[string]*dynamodb.AttributeValue{
+		"status": {S: aws.String("active")},
+		"age":    {N: aws.String("30")},
+		"tags":   {SS: []*string{aws.String("go")}},
+		"a":      {M: map[string]*dynamodb.AttributeValue{"b": {N: aws.String("0")}}},
+	}
+	f.Fuzz(func(t *testing.T, expression string) {
+		filter, err := parser.Parse(expression)
+		if err != nil {
+			return
+		}
+		if _, err := nosql.CompileFilterDynamoDB(filter); err != nil {
+			t.Fatalf("DynamoDB could not compile %q: %v", expression, err)
+		}
+		if err := nosql.CompileFilterMongoDB(filter); err != nil {
+			t.Fatalf("MongoDB could not compile %q: %v", expression, err)
+		}
+		if _, err := nosql.MatchFilterMemory(item, filter); err != nil {
+			t.Fatalf("memory could not match %q: %v", expression, err)
+		}
+	})
+}
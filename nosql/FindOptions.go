@@ -0,0 +1,10 @@
+package nosql
+
+// FindOptions are the options for Find.  IndexName forces DynamoDB to read from the
+// given index.  SortFields use the MongoDB syntax, where a leading "-" sorts in
+// descending order.  A Limit of zero returns all matching items.
+type FindOptions struct {
+	IndexName  string
+	SortFields []string
+	Limit      int
+}
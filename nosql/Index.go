@@ -0,0 +1,93 @@
+package nosql
+
+import (
+	"fmt"
+	"time"
+)
+
+type ProjectionType string
+
+const (
+	ProjectionAll      ProjectionType = "ALL"
+	ProjectionKeysOnly ProjectionType = "KEYS_ONLY"
+	ProjectionInclude  ProjectionType = "INCLUDE"
+)
+
+// Index is a secondary index of a table.  Global indexes have their own hash key, and
+// local indexes share the partition key of the table and have a range key.  Only the
+// items with the keys of an index are in the index.
+type Index struct {
+	// Name defaults to "<HashKey>-index", or "<RangeKey>-index" for local indexes.
+	Name     string
+	HashKey  string
+	RangeKey string
+	// Projection is the attributes copied into a DynamoDB index, which defaults to all
+	// of them.  ProjectionAttributes are the non-key attributes of INCLUDE projections.
+	Projection           ProjectionType
+	ProjectionAttributes []string
+	Local                bool
+	// ReadUnits and WriteUnits are the provisioned throughput of a global index, which
+	// defaults to the throughput of the table.
+	ReadUnits  int
+	WriteUnits int
+	// Unique, Sparse, and ExpireAfter are only used by MongoDB.  ExpireAfter removes
+	// items once the time in the hash key is older than the duration.
+	Unique      bool
+	Sparse      bool
+	ExpireAfter time.Duration
+}
+
+// normalizeIndexes validates the indexes of a table and returns them with the
+// defaults set.  Indexes named by attribute are hash-only global indexes, unless an
+// index with the same name is defined.
+func normalizeIndexes(table_name string, attribute_names []string, indexes []Index, partition_key Attribute) ([]Index, error) {
+	all := append([]Index{}, indexes...)
+	for _, attribute_name := range attribute_names {
+		all = append(all, Index{HashKey: attribute_name})
+	}
+	normalized := make([]Index, 0, len(all))
+	names := map[string]bool{}
+	for _, index := range all {
+		if index.Local && len(index.HashKey) == 0 {
+			index.HashKey = partition_key.Name
+		}
+		if len(index.Name) == 0 {
+			if index.Local {
+				index.Name = index.RangeKey + "-index"
+			} else {
+				index.Name = index.HashKey + "-index"
+			}
+		}
+		if len(index.Projection) == 0 {
+			index.Projection = ProjectionAll
+		}
+		switch {
+		case len(index.HashKey) == 0:
+			return nil, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("index %q of table %q requires a hash key", index.Name, table_name)}
+		case index.Local && (index.HashKey != partition_key.Name || len(index.RangeKey) == 0):
+			return nil, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("local index %q of table %q requires the partition key %q and a range key", index.Name, table_name, partition_key.Name)}
+		case index.Projection != ProjectionAll && index.Projection != ProjectionKeysOnly && index.Projection != ProjectionInclude:
+			return nil, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("index %q of table %q has unknown projection %q", index.Name, table_name, index.Projection)}
+		case index.Projection == ProjectionInclude && len(index.ProjectionAttributes) == 0:
+			return nil, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("index %q of table %q requires projection attributes", index.Name, table_name)}
+		case index.Projection != ProjectionInclude && len(index.ProjectionAttributes) > 0:
+			return nil, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("index %q of table %q has projection attributes, which require an INCLUDE projection", index.Name, table_name)}
+		}
+		if names[index.Name] {
+			continue
+		}
+		names[index.Name] = true
+		normalized = append(normalized, index)
+	}
+	return normalized, nil
+}
+
+// indexByName returns the index with the given name.
+func indexByName(indexes []Index, index_name string) (Index, bool) {
+	for _, index := range indexes {
+		if index.Name == index_name {
+			return index, true
+		}
+	}
+	return Index{}, false
+}
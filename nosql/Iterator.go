@@ -0,0 +1,40 @@
+package nosql
+
+// Iterator streams the items of a Scan or Query one at a time, so that large result
+// sets can be read in constant memory.
+//
+//	it := backend.Scan("users", "", []string{})
+//	defer it.Close()
+//	user := User{}
+//	for it.Next(&user) {
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type Iterator interface {
+	// Next unmarshals the next item into item and returns true, or returns false
+	// when there are no more items or an error occurred.
+	Next(item interface{}) bool
+	// Err returns the error that stopped the iteration, if any.
+	Err() error
+	// Close releases the resources held by the iterator.
+	Close() error
+}
+
+// errorIterator is an Iterator that fails immediately.
+type errorIterator struct {
+	err error
+}
+
+func (it *errorIterator) Next(item interface{}) bool {
+	return false
+}
+
+func (it *errorIterator) Err() error {
+	return it.err
+}
+
+func (it *errorIterator) Close() error {
+	return nil
+}
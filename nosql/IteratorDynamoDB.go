@@ -0,0 +1,54 @@
+package nosql
+
+import (
+	"context"
+)
+
+import (
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// iteratorDynamoDB follows the LastEvaluatedKey of a Scan or Query, fetching one page
+// at a time.
+type iteratorDynamoDB struct {
+	ctx   context.Context
+	fetch func(ctx context.Context, exclusive_start_key map[string]*dynamodb.AttributeValue) ([]map[string]*dynamodb.AttributeValue, map[string]*dynamodb.AttributeValue, error)
+	items []map[string]*dynamodb.AttributeValue
+	next  map[string]*dynamodb.AttributeValue
+	done  bool
+	err   error
+}
+
+func (it *iteratorDynamoDB) Next(item interface{}) bool {
+	for len(it.items) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		items, next, err := it.fetch(it.ctx, it.next)
+		if err != nil {
+			it.err = wrapDynamoDBError(err)
+			return false
+		}
+		it.items = items
+		it.next = next
+		it.done = len(next) == 0
+	}
+	err := dynamodbattribute.UnmarshalMap(it.items[0], item)
+	it.items = it.items[1:]
+	if err != nil {
+		it.err = err
+		return false
+	}
+	return true
+}
+
+func (it *iteratorDynamoDB) Err() error {
+	return it.err
+}
+
+func (it *iteratorDynamoDB) Close() error {
+	it.items = nil
+	it.done = true
+	return nil
+}
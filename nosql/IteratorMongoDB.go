@@ -0,0 +1,48 @@
+package nosql
+
+import (
+	"context"
+)
+
+import (
+	"gopkg.in/mgo.v2"
+)
+
+// iteratorMongoDB wraps an mgo.Iter and the session copy it reads from.
+type iteratorMongoDB struct {
+	ctx     context.Context
+	session *mgo.Session
+	iter    *mgo.Iter
+	err     error
+}
+
+func (it *iteratorMongoDB) Next(item interface{}) bool {
+	if it.err != nil {
+		return false
+	}
+	if err := it.ctx.Err(); err != nil {
+		it.err = err
+		return false
+	}
+	return it.iter.Next(item)
+}
+
+func (it *iteratorMongoDB) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	return wrapMongoDBError(it.iter.Err())
+}
+
+func (it *iteratorMongoDB) Close() error {
+	if it.session == nil {
+		return nil
+	}
+	err := it.iter.Close()
+	it.session.Close()
+	it.session = nil
+	if it.err == nil {
+		it.err = wrapMongoDBError(err)
+	}
+	return wrapMongoDBError(err)
+}
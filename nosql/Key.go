@@ -0,0 +1,136 @@
+package nosql
+
+import (
+	"fmt"
+)
+
+import (
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+type AttributeType string
+
+const (
+	AttributeTypeString AttributeType = "S"
+	AttributeTypeNumber AttributeType = "N"
+	AttributeTypeBinary AttributeType = "B"
+)
+
+// Attribute is the name and type of a key attribute.
+type Attribute struct {
+	Name string
+	Type AttributeType
+}
+
+// defaultPartitionKey is the partition key of tables that do not define one.
+var defaultPartitionKey = Attribute{Name: "id", Type: AttributeTypeString}
+
+// Key identifies an item by the values of its partition key and of its sort key, if
+// the table has one.
+type Key map[string]interface{}
+
+// KeyQuery selects the items of a partition in the order of their sort key, or of
+// their range key if the query uses an index.
+type KeyQuery struct {
+	// Index is the name of a secondary index, or empty to query the table.
+	Index     string
+	Partition interface{}
+	// Sort is an optional condition on the sort key, built with Eq, Lt, Lte, Gt, Gte,
+	// Between, or BeginsWith.
+	Sort       Filter
+	Descending bool
+	// Limit is the maximum number of items, or 0 for all of them.
+	Limit int
+}
+
+// keyedById returns true if a table with the partition key and the sort key is keyed
+// by id.
+func keyedById(partition_key string, sort_key string) bool {
+	return partition_key == defaultPartitionKey.Name && len(sort_key) == 0
+}
+
+// requireIdKey returns ErrInvalidQuery unless a table with the partition key and the
+// sort key is keyed by id, for the methods that take ids.
+func requireIdKey(table_name string, partition_key string, sort_key string) error {
+	if !keyedById(partition_key, sort_key) {
+		return &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("table %q is not keyed by id, so its items are identified by a Key", table_name)}
+	}
+	return nil
+}
+
+// validateKey returns an error unless the key has exactly the partition key and the
+// sort key of a table.
+func validateKey(table_name string, key Key, partition_key string, sort_key string) error {
+	expected := 1
+	if len(sort_key) > 0 {
+		expected = 2
+	}
+	if _, ok := key[partition_key]; !ok || len(key) != expected || (len(sort_key) > 0 && key[sort_key] == nil) {
+		if len(sort_key) > 0 {
+			return &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("keys of table %q require %q and %q", table_name, partition_key, sort_key)}
+		}
+		return &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("keys of table %q require %q", table_name, partition_key)}
+	}
+	return nil
+}
+
+// keyAttributeValues returns the key marshaled as DynamoDB attributes.
+func keyAttributeValues(key Key) (map[string]*dynamodb.AttributeValue, error) {
+	av := map[string]*dynamodb.AttributeValue{}
+	for k, v := range key {
+		x, err := dynamodbattribute.Marshal(v)
+		if err != nil {
+			return nil, &Error{Kind: ErrInvalidQuery, Err: err}
+		}
+		av[k] = x
+	}
+	return av, nil
+}
+
+// validateKeyQuery returns an error if the query has no partition or if the condition
+// on the sort key is not supported by DynamoDB key conditions.
+func validateKeyQuery(table_name string, query *KeyQuery, sort_key string) error {
+	if query == nil || query.Partition == nil {
+		return &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("query of table %q requires a partition", table_name)}
+	}
+	if query.Limit < 0 {
+		return &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("limit %d is negative", query.Limit)}
+	}
+	if query.Sort == nil {
+		return nil
+	}
+	if len(sort_key) == 0 {
+		return &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("table %q has no sort key", table_name)}
+	}
+	switch f := query.Sort.(type) {
+	case *FilterComparison:
+		switch f.Operator {
+		case OperatorEq, OperatorLt, OperatorLte, OperatorGt, OperatorGte, OperatorBeginsWith:
+			if f.Attribute == sort_key {
+				return nil
+			}
+		}
+	case *FilterBetween:
+		if f.Attribute == sort_key {
+			return nil
+		}
+	}
+	return &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("the condition on the sort key %q of table %q must be a comparison, between, or begins_with", sort_key, table_name)}
+}
+
+// attributeType returns the type of an attribute value, or an empty string if it is
+// not a string, number, or binary.
+func attributeType(av *dynamodb.AttributeValue) AttributeType {
+	switch {
+	case av == nil:
+		return ""
+	case av.S != nil:
+		return AttributeTypeString
+	case av.N != nil:
+		return AttributeTypeNumber
+	case av.B != nil:
+		return AttributeTypeBinary
+	}
+	return ""
+}
@@ -0,0 +1,48 @@
+package nosql
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// BackendOpener connects to a backend described by a DSN.
+type BackendOpener func(dsn string) (Backend, error)
+
+var (
+	openers_mutex sync.RWMutex
+	openers       = map[string]BackendOpener{}
+)
+
+// RegisterScheme makes a backend available to Open for DSNs with the given scheme.
+// RegisterScheme panics if the opener is nil or if the scheme is already registered.
+func RegisterScheme(scheme string, opener BackendOpener) {
+	openers_mutex.Lock()
+	defer openers_mutex.Unlock()
+	if opener == nil {
+		panic("nosql: RegisterScheme opener is nil for scheme " + scheme)
+	}
+	if _, ok := openers[scheme]; ok {
+		panic("nosql: RegisterScheme called twice for scheme " + scheme)
+	}
+	openers[scheme] = opener
+}
+
+// Open connects to the backend described by a DSN, such as
+// "dynamodb://us-west-2?endpoint=http://localhost:8000" or
+// "mongodb://localhost/main?limit=500".
+func Open(dsn string) (Backend, error) {
+	i := strings.Index(dsn, "://")
+	if i <= 0 {
+		return nil, &Error{Kind: ErrInvalidConfig, Err: errors.New("missing scheme in DSN")}
+	}
+	scheme := dsn[:i]
+	openers_mutex.RLock()
+	opener, ok := openers[scheme]
+	openers_mutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w for scheme %q", ErrUnknownBackend, scheme)
+	}
+	return opener(dsn)
+}
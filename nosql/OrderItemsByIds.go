@@ -0,0 +1,42 @@
+package nosql
+
+import (
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// uniqueIds returns the ids without duplicates, in the order they first appear.
+func uniqueIds(ids []string) []string {
+	unique := make([]string, 0, len(ids))
+	seen := map[string]bool{}
+	for _, id := range ids {
+		if !seen[id] {
+			unique = append(unique, id)
+			seen[id] = true
+		}
+	}
+	return unique
+}
+
+// orderItemsByIds returns the items found in the order of the ids, or sorted by the
+// sort fields if any, and the ids that were not found.
+func orderItemsByIds(ids []string, found map[string]map[string]*dynamodb.AttributeValue, sort_fields []string) ([]map[string]*dynamodb.AttributeValue, []string) {
+	results := make([]map[string]*dynamodb.AttributeValue, 0, len(found))
+	missing := []string{}
+	for _, id := range uniqueIds(ids) {
+		if item, ok := found[id]; ok {
+			results = append(results, item)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	sortAttributeValueMaps(results, sort_fields)
+	return results, missing
+}
+
+// missingItems returns a MissingItemsError for the ids, or nil if there are none.
+func missingItems(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return &MissingItemsError{Ids: ids}
+}
@@ -0,0 +1,49 @@
+package nosql
+
+import (
+	"sort"
+	"sync"
+)
+
+// BackendFactory connects to a backend with the given options.
+type BackendFactory func(options map[string]string) (Backend, error)
+
+var (
+	factories_mutex sync.RWMutex
+	factories       = map[string]BackendFactory{}
+)
+
+// Register makes a backend available by name to ConnectToBackend.  The built-in
+// backends register themselves as "dynamodb", "mongodb", and "memory".  Register
+// panics if the factory is nil or if a backend with the same name is already
+// registered.
+func Register(name string, factory BackendFactory) {
+	factories_mutex.Lock()
+	defer factories_mutex.Unlock()
+	if factory == nil {
+		panic("nosql: Register factory is nil for backend " + name)
+	}
+	if _, ok := factories[name]; ok {
+		panic("nosql: Register called twice for backend " + name)
+	}
+	factories[name] = factory
+}
+
+// Backends returns the sorted names of the registered backends.
+func Backends() []string {
+	factories_mutex.RLock()
+	defer factories_mutex.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func getFactory(name string) (BackendFactory, bool) {
+	factories_mutex.RLock()
+	defer factories_mutex.RUnlock()
+	factory, ok := factories[name]
+	return factory, ok
+}
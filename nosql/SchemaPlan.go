@@ -0,0 +1,72 @@
+package nosql
+
+import (
+	"fmt"
+	"strings"
+)
+
+type SchemaChangeType string
+
+const (
+	SchemaCreateTable      SchemaChangeType = "create_table"
+	SchemaAddIndex         SchemaChangeType = "add_index"
+	SchemaDeleteIndex      SchemaChangeType = "delete_index"
+	SchemaUpdateThroughput SchemaChangeType = "update_throughput"
+	SchemaUpdateTimeToLive SchemaChangeType = "update_time_to_live"
+)
+
+// SchemaChange is one step of a schema plan.  Table is the desired definition of the
+// table, and Index is the index added or deleted.
+type SchemaChange struct {
+	Type  SchemaChangeType
+	Table Table
+	Index Index
+}
+
+func (c SchemaChange) String() string {
+	switch c.Type {
+	case SchemaCreateTable:
+		return fmt.Sprintf("create table %q", c.Table.Name)
+	case SchemaAddIndex:
+		if len(c.Index.RangeKey) > 0 {
+			return fmt.Sprintf("add index %q on (%s, %s) to table %q", c.Index.Name, c.Index.HashKey, c.Index.RangeKey, c.Table.Name)
+		}
+		return fmt.Sprintf("add index %q on %s to table %q", c.Index.Name, c.Index.HashKey, c.Table.Name)
+	case SchemaDeleteIndex:
+		return fmt.Sprintf("delete index %q from table %q", c.Index.Name, c.Table.Name)
+	case SchemaUpdateThroughput:
+		if c.Table.throughput().billingMode() == BillingModePayPerRequest {
+			return fmt.Sprintf("update billing mode of table %q to %s", c.Table.Name, BillingModePayPerRequest)
+		}
+		return fmt.Sprintf("update throughput of table %q to %d read and %d write units", c.Table.Name, c.Table.ReadUnits, c.Table.WriteUnits)
+	case SchemaUpdateTimeToLive:
+		if len(c.Table.TTLAttribute) == 0 {
+			return fmt.Sprintf("disable time to live of table %q", c.Table.Name)
+		}
+		return fmt.Sprintf("enable time to live on attribute %q of table %q", c.Table.TTLAttribute, c.Table.Name)
+	}
+	return fmt.Sprintf("%s table %q", c.Type, c.Table.Name)
+}
+
+// SchemaPlan is the list of changes that make the tables of a backend match the
+// desired tables, in the order they are applied.
+type SchemaPlan struct {
+	Changes []SchemaChange
+}
+
+// Empty returns true if the tables already match.
+func (p *SchemaPlan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// String returns the changes one per line.
+func (p *SchemaPlan) String() string {
+	if p.Empty() {
+		return "no changes"
+	}
+	lines := make([]string, 0, len(p.Changes))
+	for _, c := range p.Changes {
+		lines = append(lines, c.String())
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,18 @@
+package nosql
+
+import (
+	"context"
+	"time"
+)
+
+// sleepContext pauses for the given duration or until the context is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
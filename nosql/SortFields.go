@@ -0,0 +1,16 @@
+package nosql
+
+import (
+	"strings"
+)
+
+// containsSortField returns true if the sort fields include the given attribute in
+// either direction.
+func containsSortField(sort_fields []string, attribute_name string) bool {
+	for _, f := range sort_fields {
+		if strings.TrimPrefix(strings.TrimPrefix(f, "-"), "+") == attribute_name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package nosql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// schemaBackend is implemented by the backends that support Plan and Sync.
+type schemaBackend interface {
+	// describeSchema returns the current definition of a table, or nil if the table
+	// does not exist.  The desired definition gives what cannot be described.
+	describeSchema(ctx context.Context, desired Table) (*Table, error)
+	// schemaIndex returns the parts of an index that the backend stores.
+	schemaIndex(index Index) Index
+	// applySchemaChange applies a change and waits until it is complete.
+	applySchemaChange(ctx context.Context, change SchemaChange) error
+}
+
+func Plan(backend Backend, tables []Table) (*SchemaPlan, error) {
+	return PlanContext(context.Background(), backend, tables)
+}
+
+// PlanContext compares the desired tables with the tables of the backend and returns
+// the changes that Sync would apply.  Tables and indexes that are not in the desired
+// tables are left alone, except for the indexes of the desired tables.  Changing the
+// key of a table, or adding or deleting local indexes, returns ErrNotSupported.
+func PlanContext(ctx context.Context, backend Backend, tables []Table) (*SchemaPlan, error) {
+	sb, ok := backend.(schemaBackend)
+	if !ok {
+		return nil, &Error{Kind: ErrNotSupported, Err: fmt.Errorf("backend %q does not support schema sync", backend.Type())}
+	}
+	plan := &SchemaPlan{Changes: []SchemaChange{}}
+	for _, desired := range tables {
+		changes, err := planTable(ctx, sb, desired)
+		if err != nil {
+			return nil, err
+		}
+		plan.Changes = append(plan.Changes, changes...)
+	}
+	return plan, nil
+}
+
+func Sync(backend Backend, tables []Table) (*SchemaPlan, error) {
+	return SyncContext(context.Background(), backend, tables)
+}
+
+// SyncContext plans and applies the changes that make the tables of the backend match
+// the desired tables, waiting for each change to complete.  It is idempotent, so if
+// it fails it can be run again to apply the remaining changes.  It returns the plan,
+// and defines the tables like DefineTables.
+func SyncContext(ctx context.Context, backend Backend, tables []Table) (*SchemaPlan, error) {
+	plan, err := PlanContext(ctx, backend, tables)
+	if err != nil {
+		return nil, err
+	}
+	sb := backend.(schemaBackend)
+	for _, change := range plan.Changes {
+		err := sb.applySchemaChange(ctx, change)
+		if err != nil {
+			return plan, fmt.Errorf("could not %s: %w", change, err)
+		}
+	}
+	return plan, backend.DefineTables(tables)
+}
+
+// planTable returns the changes to one table.
+func planTable(ctx context.Context, sb schemaBackend, desired Table) ([]SchemaChange, error) {
+	current, err := sb.describeSchema(ctx, desired)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return []SchemaChange{SchemaChange{Type: SchemaCreateTable, Table: desired}}, nil
+	}
+
+	d := &tableDefinitions{}
+	d.define([]Table{desired})
+	partition_key, sort_key := d.keySchema(desired.Name)
+	if current.PartitionKey != partition_key || current.SortKey != sort_key {
+		return nil, &Error{Kind: ErrNotSupported, Err: fmt.Errorf("cannot change the key of table %q", desired.Name)}
+	}
+	desired_indexes, err := d.indexes(desired.Name, desired.Indexes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Indexes are deleted before they are added, so that changed indexes are rebuilt.
+	// Throughput is updated in between, so that added indexes use the new billing mode.
+	changes := []SchemaChange{}
+	kept := []Index{}
+	for _, index := range current.SecondaryIndexes {
+		if x, ok := indexByName(desired_indexes, index.Name); !ok || !indexesEqual(sb.schemaIndex(x), index) {
+			if index.Local {
+				return nil, &Error{Kind: ErrNotSupported, Err: fmt.Errorf("cannot delete local index %q of table %q", index.Name, desired.Name)}
+			}
+			changes = append(changes, SchemaChange{Type: SchemaDeleteIndex, Table: desired, Index: index})
+		} else {
+			kept = append(kept, index)
+		}
+	}
+	if throughputChanged(current, kept, desired.throughput()) {
+		changes = append(changes, SchemaChange{Type: SchemaUpdateThroughput, Table: desired})
+	}
+	for _, index := range desired_indexes {
+		if x, ok := indexByName(current.SecondaryIndexes, index.Name); !ok || !indexesEqual(x, sb.schemaIndex(index)) {
+			if sb.schemaIndex(index).Local {
+				return nil, &Error{Kind: ErrNotSupported, Err: fmt.Errorf("cannot add local index %q to table %q", index.Name, desired.Name)}
+			}
+			changes = append(changes, SchemaChange{Type: SchemaAddIndex, Table: desired, Index: index})
+		}
+	}
+	if current.TTLAttribute != desired.TTLAttribute {
+		changes = append(changes, SchemaChange{Type: SchemaUpdateTimeToLive, Table: desired})
+	}
+	return changes, nil
+}
+
+// throughputChanged returns true if the billing mode or the units of a table, or of
+// the indexes that are kept, differ from the desired throughput.  Backends without
+// throughput describe no billing mode.
+func throughputChanged(current *Table, kept []Index, throughput *Throughput) bool {
+	mode := throughput.billingMode()
+	switch {
+	case len(current.BillingMode) == 0:
+		return false
+	case current.BillingMode != mode:
+		return true
+	case mode != BillingModeProvisioned:
+		return false
+	case current.ReadUnits != throughput.ReadUnits || current.WriteUnits != throughput.WriteUnits:
+		return true
+	}
+	for _, index := range kept {
+		x := throughput.index(index)
+		if x.ReadUnits != index.ReadUnits || x.WriteUnits != index.WriteUnits {
+			return true
+		}
+	}
+	return false
+}
+
+// indexesEqual returns true if the indexes are the same, regardless of the order of
+// their projection attributes and of their units.
+func indexesEqual(a Index, b Index) bool {
+	a.ProjectionAttributes = sortedStrings(a.ProjectionAttributes)
+	b.ProjectionAttributes = sortedStrings(b.ProjectionAttributes)
+	a.ReadUnits, a.WriteUnits = 0, 0
+	b.ReadUnits, b.WriteUnits = 0, 0
+	return reflect.DeepEqual(a, b)
+}
+
+func sortedStrings(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	sorted := append([]string{}, values...)
+	sort.Strings(sorted)
+	return sorted
+}
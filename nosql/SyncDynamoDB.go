@@ -0,0 +1,136 @@
+package nosql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func (b *BackendDynamoDB) describeSchema(ctx context.Context, desired Table) (*Table, error) {
+	description, err := b.DescribeTableContext(ctx, desired.Name)
+	if err != nil {
+		if errors.Is(err, ErrTableNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &description.Table, nil
+}
+
+// schemaIndex drops the options that are only used by MongoDB.  Units are compared by
+// planTable.
+func (b *BackendDynamoDB) schemaIndex(index Index) Index {
+	index.Unique = false
+	index.Sparse = false
+	index.ExpireAfter = 0
+	return index
+}
+
+func (b *BackendDynamoDB) applySchemaChange(ctx context.Context, change SchemaChange) error {
+	t := change.Table
+	b.definitions.define([]Table{t})
+	defer b.forgetKeys(t.Name)
+
+	input := &dynamodb.UpdateTableInput{
+		TableName: aws.String(t.Name),
+	}
+	switch change.Type {
+	case SchemaCreateTable:
+		err := b.CreateTableContext(ctx, t.Name, t.Indexes, t.ReadUnits, t.WriteUnits)
+		if err != nil || b.wait_for_active {
+			return err
+		}
+		return b.waitForTable(ctx, t.Name)
+	case SchemaAddIndex:
+		index := t.throughput().index(change.Index)
+		ad := []*dynamodb.AttributeDefinition{}
+		for _, attribute_name := range []string{change.Index.HashKey, change.Index.RangeKey} {
+			if len(attribute_name) > 0 {
+				ad = append(ad, &dynamodb.AttributeDefinition{
+					AttributeName: aws.String(attribute_name),
+					AttributeType: aws.String(string(b.definitions.attributeType(t.Name, attribute_name))),
+				})
+			}
+		}
+		input.AttributeDefinitions = ad
+		input.GlobalSecondaryIndexUpdates = []*dynamodb.GlobalSecondaryIndexUpdate{
+			&dynamodb.GlobalSecondaryIndexUpdate{
+				Create: &dynamodb.CreateGlobalSecondaryIndexAction{
+					IndexName:             aws.String(change.Index.Name),
+					KeySchema:             keySchemaDynamoDB(change.Index.HashKey, change.Index.RangeKey),
+					Projection:            projectionDynamoDB(change.Index),
+					ProvisionedThroughput: provisionedThroughputDynamoDB(index.ReadUnits, index.WriteUnits),
+				},
+			},
+		}
+	case SchemaDeleteIndex:
+		input.GlobalSecondaryIndexUpdates = []*dynamodb.GlobalSecondaryIndexUpdate{
+			&dynamodb.GlobalSecondaryIndexUpdate{
+				Delete: &dynamodb.DeleteGlobalSecondaryIndexAction{
+					IndexName: aws.String(change.Index.Name),
+				},
+			},
+		}
+	case SchemaUpdateThroughput:
+		return b.UpdateThroughputContext(ctx, t.Name, t.throughput())
+	case SchemaUpdateTimeToLive:
+		return b.UpdateTimeToLiveContext(ctx, t.Name, t.TTLAttribute)
+	}
+
+	_, err := b.dynamodb_client.UpdateTableWithContext(ctx, input)
+	if err != nil {
+		return wrapDynamoDBError(err)
+	}
+	return b.waitForTable(ctx, t.Name)
+}
+
+// waitForTable waits until a table and all of its global secondary indexes are active,
+// which is also when deleted indexes are gone.
+func (b *BackendDynamoDB) waitForTable(ctx context.Context, table_name string) error {
+	return waitFor(ctx, b.wait_timeout, fmt.Sprintf("table %q to become active", table_name), func(ctx context.Context) (bool, error) {
+		result, err := b.dynamodb_client.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+			TableName: aws.String(table_name),
+		})
+		if err != nil {
+			return false, wrapDynamoDBError(err)
+		}
+		return tableActiveDynamoDB(result.Table), nil
+	})
+}
+
+// waitForTableDeleted waits until a table no longer exists.
+func (b *BackendDynamoDB) waitForTableDeleted(ctx context.Context, table_name string) error {
+	return waitFor(ctx, b.wait_timeout, fmt.Sprintf("table %q to be deleted", table_name), func(ctx context.Context) (bool, error) {
+		result, err := b.dynamodb_client.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+			TableName: aws.String(table_name),
+		})
+		if err != nil {
+			err = wrapDynamoDBError(err)
+			if errors.Is(err, ErrTableNotFound) {
+				return true, nil
+			}
+			return false, err
+		}
+		if aws.StringValue(result.Table.TableStatus) != dynamodb.TableStatusDeleting {
+			return false, fmt.Errorf("table %q has status %s while deleting", table_name, aws.StringValue(result.Table.TableStatus))
+		}
+		return false, nil
+	})
+}
+
+func tableActiveDynamoDB(t *dynamodb.TableDescription) bool {
+	if aws.StringValue(t.TableStatus) != dynamodb.TableStatusActive {
+		return false
+	}
+	for _, index := range t.GlobalSecondaryIndexes {
+		if aws.StringValue(index.IndexStatus) != dynamodb.IndexStatusActive || aws.BoolValue(index.Backfilling) {
+			return false
+		}
+	}
+	return true
+}
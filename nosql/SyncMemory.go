@@ -0,0 +1,47 @@
+package nosql
+
+import (
+	"context"
+	"errors"
+)
+
+func (b *BackendMemory) describeSchema(ctx context.Context, desired Table) (*Table, error) {
+	description, err := b.DescribeTableContext(ctx, desired.Name)
+	if err != nil {
+		if errors.Is(err, ErrTableNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &description.Table, nil
+}
+
+func (b *BackendMemory) schemaIndex(index Index) Index {
+	return index
+}
+
+func (b *BackendMemory) applySchemaChange(ctx context.Context, change SchemaChange) error {
+	switch change.Type {
+	case SchemaCreateTable:
+		return b.CreateTablesContext(ctx, []Table{change.Table})
+	case SchemaUpdateThroughput:
+		return b.UpdateThroughputContext(ctx, change.Table.Name, change.Table.throughput())
+	case SchemaUpdateTimeToLive:
+		return b.UpdateTimeToLiveContext(ctx, change.Table.Name, change.Table.TTLAttribute)
+	}
+	return b.write(ctx, change.Table.Name, func(t *tableMemory) error {
+		switch change.Type {
+		case SchemaAddIndex:
+			t.indexes = append(t.indexes, change.Table.throughput().index(change.Index))
+		case SchemaDeleteIndex:
+			indexes := make([]Index, 0, len(t.indexes))
+			for _, index := range t.indexes {
+				if index.Name != change.Index.Name {
+					indexes = append(indexes, index)
+				}
+			}
+			t.indexes = indexes
+		}
+		return nil
+	})
+}
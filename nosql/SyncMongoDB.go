@@ -0,0 +1,59 @@
+package nosql
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+import (
+	"gopkg.in/mgo.v2"
+)
+
+func (b *BackendMongoDB) describeSchema(ctx context.Context, desired Table) (*Table, error) {
+	d := &tableDefinitions{}
+	d.define([]Table{desired})
+	partition_key, sort_key := d.keySchema(desired.Name)
+	description, err := b.describeTable(ctx, desired.Name, partition_key, sort_key)
+	if err != nil {
+		if errors.Is(err, ErrTableNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &description.Table, nil
+}
+
+// schemaIndex drops the options that are only used by DynamoDB.  Local indexes are
+// compound indexes on the partition key and the range key.
+func (b *BackendMongoDB) schemaIndex(index Index) Index {
+	index.Projection = ProjectionAll
+	index.ProjectionAttributes = nil
+	index.Local = false
+	index.ExpireAfter = index.ExpireAfter.Truncate(time.Second)
+	return index
+}
+
+func (b *BackendMongoDB) applySchemaChange(ctx context.Context, change SchemaChange) error {
+	switch change.Type {
+	case SchemaCreateTable:
+		return b.CreateTablesContext(ctx, []Table{change.Table})
+	case SchemaAddIndex:
+		return b.withCollection(ctx, change.Table.Name, func(c *mgo.Collection) error {
+			return c.EnsureIndex(mgo.Index{
+				Name:        change.Index.Name,
+				Key:         keyMongoDB(change.Index.HashKey, change.Index.RangeKey),
+				Unique:      change.Index.Unique,
+				Sparse:      change.Index.Sparse,
+				ExpireAfter: change.Index.ExpireAfter,
+			})
+		})
+	case SchemaDeleteIndex:
+		return b.withCollection(ctx, change.Table.Name, func(c *mgo.Collection) error {
+			return c.DropIndexName(change.Index.Name)
+		})
+	case SchemaUpdateTimeToLive:
+		return b.UpdateTimeToLiveContext(ctx, change.Table.Name, change.Table.TTLAttribute)
+	}
+	return nil
+}
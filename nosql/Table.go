@@ -1,8 +1,34 @@
 package nosql
 
 type Table struct {
-	Name       string
-	Indexes    []string
-	ReadUnits  int
-	WriteUnits int
+	Name    string
+	Indexes []string
+	// ReadUnits and WriteUnits are the provisioned throughput of a DynamoDB table and of
+	// its global indexes.  BillingMode defaults to PROVISIONED, or to PAY_PER_REQUEST if
+	// the table has no units.
+	ReadUnits   int
+	WriteUnits  int
+	BillingMode BillingMode
+	// PartitionKey and SortKey are the primary key of the table.  Tables without a
+	// partition key are keyed by a string "id", and tables without a sort key only by
+	// their partition key.
+	PartitionKey Attribute
+	SortKey      Attribute
+	// Attributes declares the types of the attributes of the indexes.  Attributes that
+	// are not declared are strings.
+	Attributes []Attribute
+	// SecondaryIndexes defines indexes in addition to the hash-only global indexes of
+	// the attributes in Indexes, which are named "<attribute>-index".
+	SecondaryIndexes []Index
+	// VersionAttribute enables optimistic locking.  UpdateItemById and ReplaceItem only
+	// write an item if its stored version equals the given version, and increment it.
+	// The version attribute is not stored with the table, so every process that writes
+	// to the table must define it with DefineTables or CreateTables, or connect with the
+	// version_attribute DSN parameter.  Otherwise writes skip the version check.
+	VersionAttribute string
+	// TTLAttribute is the attribute with the time at which items expire, which is a
+	// time.Time, or a *time.Time that is nil for items that never expire.  It is stored
+	// as a Unix time in seconds by DynamoDB and as a date by MongoDB.  Expired items are
+	// removed in the background, and are not returned in the meantime.
+	TTLAttribute string
 }
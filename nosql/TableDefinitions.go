@@ -0,0 +1,174 @@
+package nosql
+
+import (
+	"sync"
+	"time"
+)
+
+// ttlLookupInterval is how long a table that is not defined is read as having no time
+// to live attribute, before the attribute is read from the table again.
+const ttlLookupInterval = time.Minute
+
+// tableDefinitions holds the definitions of the tables of a backend, for the options
+// that DynamoDB and MongoDB do not store with the table, like version attributes, and
+// for the options that CreateTable does not take, like key schemas.
+type tableDefinitions struct {
+	mutex  sync.RWMutex
+	tables map[string]Table
+	// default_version_attribute is the version attribute of the tables that are not
+	// defined, from the configuration of the backend.
+	default_version_attribute string
+	// ttl_misses are the times at which the time to live attributes of the tables that
+	// are not defined were not found.
+	ttl_misses map[string]time.Time
+}
+
+func (d *tableDefinitions) define(tables []Table) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.tables == nil {
+		d.tables = map[string]Table{}
+	}
+	for _, t := range tables {
+		d.tables[t.Name] = t
+	}
+}
+
+// defineDefaultVersionAttribute sets the version attribute of the tables that are not
+// defined.
+func (d *tableDefinitions) defineDefaultVersionAttribute(version_attribute string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.default_version_attribute = version_attribute
+}
+
+// lookupTTLAttribute returns true if the time to live attribute of a table should be
+// read from the table, which is when the table is not defined and the attribute was
+// not found within ttlLookupInterval.
+func (d *tableDefinitions) lookupTTLAttribute(table_name string, now time.Time) bool {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	if _, ok := d.tables[table_name]; ok {
+		return false
+	}
+	missed, ok := d.ttl_misses[table_name]
+	return !ok || now.Sub(missed) >= ttlLookupInterval
+}
+
+// missTTLAttribute records that the time to live attribute of a table that is not
+// defined was not found at the time.
+func (d *tableDefinitions) missTTLAttribute(table_name string, now time.Time) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.ttl_misses == nil {
+		d.ttl_misses = map[string]time.Time{}
+	}
+	d.ttl_misses[table_name] = now
+}
+
+// versionAttribute returns the version attribute of a table, or an empty string if
+// the table is not versioned.
+func (d *tableDefinitions) versionAttribute(table_name string) string {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	t, ok := d.tables[table_name]
+	if !ok {
+		return d.default_version_attribute
+	}
+	return t.VersionAttribute
+}
+
+// ttlAttribute returns the time to live attribute of a table, or an empty string if
+// items do not expire.
+func (d *tableDefinitions) ttlAttribute(table_name string) string {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return d.tables[table_name].TTLAttribute
+}
+
+// defineTTLAttribute changes the time to live attribute of the definition of a table.
+func (d *tableDefinitions) defineTTLAttribute(table_name string, ttl_attribute string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.tables == nil {
+		d.tables = map[string]Table{}
+	}
+	t, ok := d.tables[table_name]
+	if !ok {
+		t = Table{Name: table_name, VersionAttribute: d.default_version_attribute}
+	}
+	t.TTLAttribute = ttl_attribute
+	d.tables[table_name] = t
+}
+
+// keySchema returns the partition key and the sort key of a table.  The name of the
+// sort key is empty if the table has none.
+func (d *tableDefinitions) keySchema(table_name string) (Attribute, Attribute) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	t := d.tables[table_name]
+	if len(t.PartitionKey.Name) == 0 {
+		return defaultPartitionKey, Attribute{}
+	}
+	partition_key := t.PartitionKey
+	if len(partition_key.Type) == 0 {
+		partition_key.Type = AttributeTypeString
+	}
+	sort_key := t.SortKey
+	if len(sort_key.Name) > 0 && len(sort_key.Type) == 0 {
+		sort_key.Type = AttributeTypeString
+	}
+	return partition_key, sort_key
+}
+
+// attributeType returns the type of an attribute of a table, which is a string unless
+// the attribute is declared as a key or in the attributes of the table.
+func (d *tableDefinitions) attributeType(table_name string, attribute_name string) AttributeType {
+	partition_key, sort_key := d.keySchema(table_name)
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	switch attribute_name {
+	case partition_key.Name:
+		return partition_key.Type
+	case sort_key.Name:
+		return sort_key.Type
+	}
+	for _, a := range d.tables[table_name].Attributes {
+		if a.Name == attribute_name && len(a.Type) > 0 {
+			return a.Type
+		}
+	}
+	return AttributeTypeString
+}
+
+// index returns the index of a table with the given name, for backends that do not
+// store the indexes of tables.
+func (d *tableDefinitions) index(table_name string, index_name string) (Index, bool) {
+	d.mutex.RLock()
+	attribute_names := d.tables[table_name].Indexes
+	d.mutex.RUnlock()
+	indexes, err := d.indexes(table_name, attribute_names)
+	if err != nil {
+		return Index{}, false
+	}
+	return indexByName(indexes, index_name)
+}
+
+// indexes returns the indexes of a table, which are the indexes named by attribute
+// and the secondary indexes of the definition of the table.
+func (d *tableDefinitions) indexes(table_name string, attribute_names []string) ([]Index, error) {
+	partition_key, _ := d.keySchema(table_name)
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return normalizeIndexes(table_name, attribute_names, d.tables[table_name].SecondaryIndexes, partition_key)
+}
+
+// throughput returns the throughput of a table with the given units.
+func (d *tableDefinitions) throughput(table_name string, read_units int, write_units int) *Throughput {
+	d.mutex.RLock()
+	t := d.tables[table_name]
+	d.mutex.RUnlock()
+	t.ReadUnits = read_units
+	t.WriteUnits = write_units
+	return t.throughput()
+}
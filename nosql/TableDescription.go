@@ -0,0 +1,22 @@
+package nosql
+
+type TableStatus string
+
+const (
+	TableStatusCreating TableStatus = "CREATING"
+	TableStatusActive   TableStatus = "ACTIVE"
+	TableStatusUpdating TableStatus = "UPDATING"
+	TableStatusDeleting TableStatus = "DELETING"
+)
+
+// TableDescription is a table as stored by a backend.  The table has its key schema,
+// its secondary indexes, and its throughput, but not the options that are only
+// defined with DefineTables, like version attributes.  All indexes are in
+// SecondaryIndexes, including the indexes named by attribute.
+type TableDescription struct {
+	Table
+	Status TableStatus
+	// ItemCount and SizeBytes are estimates, which DynamoDB updates every six hours.
+	ItemCount int64
+	SizeBytes int64
+}
@@ -0,0 +1,92 @@
+package nosql
+
+import (
+	"fmt"
+)
+
+type BillingMode string
+
+const (
+	BillingModeProvisioned   BillingMode = "PROVISIONED"
+	BillingModePayPerRequest BillingMode = "PAY_PER_REQUEST"
+)
+
+// Throughput is the billing mode and capacity of a table and of its global indexes.
+// Only DynamoDB uses throughput.
+type Throughput struct {
+	// BillingMode defaults to PROVISIONED, or to PAY_PER_REQUEST if there are no units.
+	BillingMode BillingMode
+	ReadUnits   int
+	WriteUnits  int
+	// Indexes are the units of global indexes by name, which default to the units of
+	// the table.  Their billing modes are ignored.
+	Indexes map[string]Throughput
+}
+
+func (t *Throughput) billingMode() BillingMode {
+	if len(t.BillingMode) > 0 {
+		return t.BillingMode
+	}
+	if t.ReadUnits == 0 && t.WriteUnits == 0 {
+		return BillingModePayPerRequest
+	}
+	return BillingModeProvisioned
+}
+
+// index returns the index with its units, which are zero for local indexes and for
+// tables billed per request.
+func (t *Throughput) index(index Index) Index {
+	index.ReadUnits = 0
+	index.WriteUnits = 0
+	if index.Local || t.billingMode() != BillingModeProvisioned {
+		return index
+	}
+	index.ReadUnits = t.ReadUnits
+	index.WriteUnits = t.WriteUnits
+	if x, ok := t.Indexes[index.Name]; ok {
+		index.ReadUnits = x.ReadUnits
+		index.WriteUnits = x.WriteUnits
+	}
+	return index
+}
+
+// validate returns an error if the billing mode is unknown or if provisioned units are
+// not positive.
+func (t *Throughput) validate(table_name string) error {
+	switch t.billingMode() {
+	case BillingModePayPerRequest:
+		return nil
+	case BillingModeProvisioned:
+		if t.ReadUnits <= 0 || t.WriteUnits <= 0 {
+			return &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("provisioned table %q requires read and write units", table_name)}
+		}
+		for index_name, x := range t.Indexes {
+			if x.ReadUnits <= 0 || x.WriteUnits <= 0 {
+				return &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("provisioned index %q of table %q requires read and write units", index_name, table_name)}
+			}
+		}
+		return nil
+	}
+	return &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("table %q has unknown billing mode %q", table_name, t.BillingMode)}
+}
+
+// throughput returns the throughput of a table, with the units of the secondary
+// indexes that define them.
+func (t Table) throughput() *Throughput {
+	throughput := &Throughput{
+		BillingMode: t.BillingMode,
+		ReadUnits:   t.ReadUnits,
+		WriteUnits:  t.WriteUnits,
+		Indexes:     map[string]Throughput{},
+	}
+	for _, index := range t.SecondaryIndexes {
+		if !index.Local && (index.ReadUnits > 0 || index.WriteUnits > 0) {
+			index_name := index.Name
+			if len(index_name) == 0 {
+				index_name = index.HashKey + "-index"
+			}
+			throughput.Indexes[index_name] = Throughput{ReadUnits: index.ReadUnits, WriteUnits: index.WriteUnits}
+		}
+	}
+	return throughput
+}
@@ -0,0 +1,175 @@
+package nosql
+
+import (
+	"errors"
+	"fmt"
+)
+
+import (
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// transactionLimit is the maximum number of operations in a transaction, which is
+// the limit of TransactWriteItems and TransactGetItems.
+const transactionLimit = 100
+
+// Transaction is a set of writes to items in one or more tables that either all
+// succeed or all fail.  Transactions are built with Put, Update, Delete, and
+// ConditionCheck, and If adds a condition to the last operation.  An item can only be
+// written by one operation of a transaction.
+type Transaction struct {
+	Operations []TransactionOperation
+}
+
+type TransactionOperator string
+
+const (
+	TransactionPut            TransactionOperator = "put"
+	TransactionUpdate         TransactionOperator = "update"
+	TransactionDelete         TransactionOperator = "delete"
+	TransactionConditionCheck TransactionOperator = "condition_check"
+)
+
+// TransactionOperation writes or checks one item.  Item is only used by Put, which
+// reads the id from the item, and Update is only used by Update.  The operation fails
+// the transaction if the Condition does not match the current item.
+type TransactionOperation struct {
+	Operator  TransactionOperator
+	Table     string
+	Id        string
+	Item      interface{}
+	Update    *Update
+	Condition Filter
+}
+
+// TransactionRead reads one item of a read transaction into Item.
+type TransactionRead struct {
+	Table string
+	Id    string
+	Item  interface{}
+}
+
+func NewTransaction() *Transaction {
+	return &Transaction{Operations: []TransactionOperation{}}
+}
+
+func (t *Transaction) add(op TransactionOperation) *Transaction {
+	t.Operations = append(t.Operations, op)
+	return t
+}
+
+// Put writes an item, overwriting the existing item with the same id.
+func (t *Transaction) Put(table_name string, item interface{}) *Transaction {
+	return t.add(TransactionOperation{Operator: TransactionPut, Table: table_name, Item: item})
+}
+
+// Update applies an update to an item, following the rules of UpdateItem.
+func (t *Transaction) Update(table_name string, id string, update *Update) *Transaction {
+	return t.add(TransactionOperation{Operator: TransactionUpdate, Table: table_name, Id: id, Update: update})
+}
+
+// Delete removes an item.  Deleting a missing item is not an error.
+func (t *Transaction) Delete(table_name string, id string) *Transaction {
+	return t.add(TransactionOperation{Operator: TransactionDelete, Table: table_name, Id: id})
+}
+
+// ConditionCheck fails the transaction unless the item matches the condition.
+func (t *Transaction) ConditionCheck(table_name string, id string, condition Filter) *Transaction {
+	return t.add(TransactionOperation{Operator: TransactionConditionCheck, Table: table_name, Id: id, Condition: condition})
+}
+
+// If sets the condition of the last operation.  A missing item has no attributes.
+func (t *Transaction) If(condition Filter) *Transaction {
+	if len(t.Operations) > 0 {
+		t.Operations[len(t.Operations)-1].Condition = condition
+	}
+	return t
+}
+
+// items validates the transaction and returns the id of the item of every operation,
+// along with the marshaled items of the puts.
+func (t *Transaction) items() ([]string, []map[string]*dynamodb.AttributeValue, error) {
+	if t == nil || len(t.Operations) == 0 {
+		return nil, nil, &Error{Kind: ErrInvalidQuery, Err: errors.New("transaction is empty")}
+	}
+	if len(t.Operations) > transactionLimit {
+		return nil, nil, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("transaction has %d operations, more than the limit of %d", len(t.Operations), transactionLimit)}
+	}
+	ids := make([]string, len(t.Operations))
+	items := make([]map[string]*dynamodb.AttributeValue, len(t.Operations))
+	seen := map[string]bool{}
+	for i, op := range t.Operations {
+		if len(op.Table) == 0 {
+			return nil, nil, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("operation %d has no table", i)}
+		}
+		switch op.Operator {
+		case TransactionPut:
+			av, err := dynamodbattribute.MarshalMap(op.Item)
+			if err != nil {
+				return nil, nil, &Error{Kind: ErrInvalidQuery, Err: err}
+			}
+			id, err := itemId(av)
+			if err != nil {
+				return nil, nil, err
+			}
+			ids[i] = id
+			items[i] = av
+		case TransactionUpdate:
+			if op.Update == nil {
+				return nil, nil, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("operation %d has no update", i)}
+			}
+			ids[i] = op.Id
+		case TransactionConditionCheck:
+			if op.Condition == nil {
+				return nil, nil, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("operation %d has no condition", i)}
+			}
+			ids[i] = op.Id
+		case TransactionDelete:
+			ids[i] = op.Id
+		default:
+			return nil, nil, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("unknown transaction operator %q", op.Operator)}
+		}
+		if len(ids[i]) == 0 {
+			return nil, nil, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("operation %d has no id", i)}
+		}
+		// DynamoDB rejects transactions with more than one operation on an item.
+		key := op.Table + "\x00" + ids[i]
+		if seen[key] {
+			return nil, nil, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("item %q of table %q is written more than once", ids[i], op.Table)}
+		}
+		seen[key] = true
+	}
+	return ids, items, nil
+}
+
+// transactionError returns a TransactionError for the failed results, or nil if
+// there are none.
+func transactionError(results []BulkResult) error {
+	failed := make([]BulkResult, 0)
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return &TransactionError{Failed: failed}
+}
+
+// validateReads returns an error if the reads are empty, too many, or incomplete.
+func validateReads(reads []TransactionRead) error {
+	if len(reads) == 0 {
+		return &Error{Kind: ErrInvalidQuery, Err: errors.New("transaction is empty")}
+	}
+	if len(reads) > transactionLimit {
+		return &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("transaction has %d reads, more than the limit of %d", len(reads), transactionLimit)}
+	}
+	for i, r := range reads {
+		if len(r.Table) == 0 || len(r.Id) == 0 || r.Item == nil {
+			return &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("read %d requires a table, an id, and an item", i)}
+		}
+	}
+	return nil
+}
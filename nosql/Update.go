@@ -0,0 +1,182 @@
+package nosql
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Update is a backend-neutral set of changes to the attributes of an item.  Updates
+// are built with Set, Unset, Increment, Append, Prepend, AddToSet, RemoveFromSet, and
+// SetIfNotExists, and are compiled to update expressions for DynamoDB and to update
+// operators for MongoDB.  Attribute names may use dots to refer to nested attributes.
+type Update struct {
+	Operations []UpdateOperation
+	// Version is the expected version of the item, for tables with a version attribute.
+	Version *int64
+}
+
+type UpdateOperator string
+
+const (
+	UpdateSet            UpdateOperator = "set"
+	UpdateUnset          UpdateOperator = "unset"
+	UpdateIncrement      UpdateOperator = "increment"
+	UpdateAppend         UpdateOperator = "append"
+	UpdatePrepend        UpdateOperator = "prepend"
+	UpdateAddToSet       UpdateOperator = "add_to_set"
+	UpdateRemoveFromSet  UpdateOperator = "remove_from_set"
+	UpdateSetIfNotExists UpdateOperator = "set_if_not_exists"
+)
+
+// UpdateOperation changes one attribute.  The value of Append, Prepend, AddToSet, and
+// RemoveFromSet is a []interface{} of the elements.
+type UpdateOperation struct {
+	Operator  UpdateOperator
+	Attribute string
+	Value     interface{}
+}
+
+func NewUpdate() *Update {
+	return &Update{Operations: []UpdateOperation{}}
+}
+
+func (u *Update) add(operator UpdateOperator, attribute_name string, value interface{}) *Update {
+	u.Operations = append(u.Operations, UpdateOperation{Operator: operator, Attribute: attribute_name, Value: value})
+	return u
+}
+
+// Set sets the attribute to a value of any type that can be marshaled.
+func (u *Update) Set(attribute_name string, value interface{}) *Update {
+	return u.add(UpdateSet, attribute_name, value)
+}
+
+// Unset removes the attribute.
+func (u *Update) Unset(attribute_name string) *Update {
+	return u.add(UpdateUnset, attribute_name, nil)
+}
+
+// Increment adds a number to a numeric attribute.  A missing attribute is 0.  Use a
+// negative number to decrement.
+func (u *Update) Increment(attribute_name string, value interface{}) *Update {
+	return u.add(UpdateIncrement, attribute_name, value)
+}
+
+// Append adds the values to the end of a list attribute.  A missing attribute is an
+// empty list.
+func (u *Update) Append(attribute_name string, values ...interface{}) *Update {
+	return u.add(UpdateAppend, attribute_name, values)
+}
+
+// Prepend adds the values to the start of a list attribute.  A missing attribute is an
+// empty list.
+func (u *Update) Prepend(attribute_name string, values ...interface{}) *Update {
+	return u.add(UpdatePrepend, attribute_name, values)
+}
+
+// AddToSet adds the values that are not already present to a set attribute.  The
+// values must all be strings or all be numbers.  DynamoDB stores the attribute as a
+// string or number set and MongoDB as an array.
+func (u *Update) AddToSet(attribute_name string, values ...interface{}) *Update {
+	return u.add(UpdateAddToSet, attribute_name, values)
+}
+
+// RemoveFromSet removes the values from a set attribute.
+func (u *Update) RemoveFromSet(attribute_name string, values ...interface{}) *Update {
+	return u.add(UpdateRemoveFromSet, attribute_name, values)
+}
+
+// SetIfNotExists sets the attribute to the value only if the attribute is missing.
+func (u *Update) SetIfNotExists(attribute_name string, value interface{}) *Update {
+	return u.add(UpdateSetIfNotExists, attribute_name, value)
+}
+
+// ExpectVersion sets the version the item must be at, for tables with a version
+// attribute.
+func (u *Update) ExpectVersion(version int64) *Update {
+	u.Version = &version
+	return u
+}
+
+// validate checks the update of a table with the given key and version attribute.
+func (u *Update) validate(table_name string, key string, version_attribute string) error {
+	if u == nil || (len(u.Operations) == 0 && u.Version == nil) {
+		return &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("update of table %q is empty", table_name)}
+	}
+	if len(version_attribute) > 0 && u.Version == nil {
+		return &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("updates of table %q require the version in %q", table_name, version_attribute)}
+	}
+	if len(version_attribute) == 0 && u.Version != nil {
+		return &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("table %q has no version attribute", table_name)}
+	}
+	seen := map[string]bool{}
+	for _, op := range u.Operations {
+		switch {
+		case len(op.Attribute) == 0:
+			return &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("%s requires an attribute", op.Operator)}
+		case op.Attribute == key:
+			return &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("cannot update the %s attribute", key)}
+		case op.Attribute == version_attribute:
+			return &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("cannot update the version attribute %q", version_attribute)}
+		case seen[op.Attribute]:
+			return &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("attribute %q is updated more than once", op.Attribute)}
+		}
+		seen[op.Attribute] = true
+		switch op.Operator {
+		case UpdateSet, UpdateUnset, UpdateSetIfNotExists:
+		case UpdateIncrement:
+			if !isNumber(op.Value) {
+				return &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("increment of %q requires a number, not %T", op.Attribute, op.Value)}
+			}
+		case UpdateAppend, UpdatePrepend, UpdateAddToSet, UpdateRemoveFromSet:
+			if values, ok := op.Value.([]interface{}); !ok || len(values) == 0 {
+				return &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("%s of %q requires at least one value", op.Operator, op.Attribute)}
+			}
+		default:
+			return &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("unknown update operator %q", op.Operator)}
+		}
+	}
+	return nil
+}
+
+// isNumber returns true if the value is of a numeric type.
+func isNumber(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// updateFromValues returns the update of UpdateItemById, which sets the attributes to
+// the values and unsets the attributes with nil values.  The value of the version
+// attribute is the expected version.
+func updateFromValues(values map[string]interface{}, version_attribute string) (*Update, error) {
+	names := make([]string, 0, len(values))
+	for k := range values {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	u := NewUpdate()
+	for _, k := range names {
+		v := values[k]
+		switch {
+		case len(version_attribute) > 0 && k == version_attribute:
+			version, err := versionValue(v)
+			if err != nil {
+				return nil, err
+			}
+			u.ExpectVersion(version)
+		case v == nil:
+			u.Unset(k)
+		default:
+			u.Set(k, v)
+		}
+	}
+	return u, nil
+}
@@ -0,0 +1,108 @@
+package nosql
+
+import (
+	"fmt"
+	"strings"
+)
+
+import (
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// update compiles the operations of an update into a DynamoDB update expression.
+func (e *expressionDynamoDB) update(operations []UpdateOperation) (string, error) {
+	set := []string{}
+	remove := []string{}
+	add := []string{}
+	del := []string{}
+	for _, op := range operations {
+		n := e.name(op.Attribute)
+		switch op.Operator {
+		case UpdateSet:
+			v, err := e.value(op.Value)
+			if err != nil {
+				return "", err
+			}
+			set = append(set, n+" = "+v)
+		case UpdateUnset:
+			remove = append(remove, n)
+		case UpdateIncrement:
+			v, err := e.value(op.Value)
+			if err != nil {
+				return "", err
+			}
+			add = append(add, n+" "+v)
+		case UpdateAppend, UpdatePrepend:
+			v, err := e.value(op.Value)
+			if err != nil {
+				return "", err
+			}
+			empty := e.attributeValue(&dynamodb.AttributeValue{L: []*dynamodb.AttributeValue{}})
+			if op.Operator == UpdateAppend {
+				set = append(set, n+" = list_append(if_not_exists("+n+", "+empty+"), "+v+")")
+			} else {
+				set = append(set, n+" = list_append("+v+", if_not_exists("+n+", "+empty+"))")
+			}
+		case UpdateAddToSet, UpdateRemoveFromSet:
+			av, err := setAttributeValue(op.Attribute, op.Value.([]interface{}))
+			if err != nil {
+				return "", err
+			}
+			if op.Operator == UpdateAddToSet {
+				add = append(add, n+" "+e.attributeValue(av))
+			} else {
+				del = append(del, n+" "+e.attributeValue(av))
+			}
+		case UpdateSetIfNotExists:
+			v, err := e.value(op.Value)
+			if err != nil {
+				return "", err
+			}
+			set = append(set, n+" = if_not_exists("+n+", "+v+")")
+		default:
+			return "", &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("unknown update operator %q", op.Operator)}
+		}
+	}
+	clauses := []string{}
+	if len(set) > 0 {
+		clauses = append(clauses, "SET "+strings.Join(set, ", "))
+	}
+	if len(remove) > 0 {
+		clauses = append(clauses, "REMOVE "+strings.Join(remove, ", "))
+	}
+	if len(add) > 0 {
+		clauses = append(clauses, "ADD "+strings.Join(add, ", "))
+	}
+	if len(del) > 0 {
+		clauses = append(clauses, "DELETE "+strings.Join(del, ", "))
+	}
+	return strings.Join(clauses, " "), nil
+}
+
+// setAttributeValue returns the string or number set of the values.
+func setAttributeValue(attribute_name string, values []interface{}) (*dynamodb.AttributeValue, error) {
+	set := &dynamodb.AttributeValue{}
+	seen := map[string]bool{}
+	for _, v := range values {
+		av, err := dynamodbattribute.Marshal(v)
+		if err != nil {
+			return nil, &Error{Kind: ErrInvalidQuery, Err: err}
+		}
+		// DynamoDB rejects sets with duplicate elements.
+		if (av.S != nil && seen["S"+*av.S]) || (av.N != nil && seen["N"+*av.N]) {
+			continue
+		}
+		switch {
+		case av.S != nil && set.NS == nil:
+			set.SS = append(set.SS, av.S)
+			seen["S"+*av.S] = true
+		case av.N != nil && set.SS == nil:
+			set.NS = append(set.NS, av.N)
+			seen["N"+*av.N] = true
+		default:
+			return nil, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("set %q requires all strings or all numbers", attribute_name)}
+		}
+	}
+	return set, nil
+}
@@ -0,0 +1,198 @@
+package nosql
+
+import (
+	"fmt"
+	"strings"
+)
+
+import (
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// applyUpdateMemory applies the operations of an update to an item with the semantics
+// of DynamoDB.  Nested maps are copied before they are changed, so that the items
+// returned by earlier reads are not modified.
+func applyUpdateMemory(item map[string]*dynamodb.AttributeValue, operations []UpdateOperation) error {
+	for _, op := range operations {
+		op := op
+		var fn func(av *dynamodb.AttributeValue) (*dynamodb.AttributeValue, error)
+		switch op.Operator {
+		case UpdateSet:
+			fn = func(av *dynamodb.AttributeValue) (*dynamodb.AttributeValue, error) {
+				return marshalUpdateValue(op.Value)
+			}
+		case UpdateUnset:
+			fn = func(av *dynamodb.AttributeValue) (*dynamodb.AttributeValue, error) {
+				return nil, nil
+			}
+		case UpdateIncrement:
+			fn = func(av *dynamodb.AttributeValue) (*dynamodb.AttributeValue, error) {
+				x, err := marshalUpdateValue(op.Value)
+				if err != nil || av == nil {
+					return x, err
+				}
+				if av.N == nil {
+					return nil, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("attribute %q is not a number", op.Attribute)}
+				}
+				sum, err := addNumbers(*av.N, *x.N)
+				if err != nil {
+					return nil, err
+				}
+				return &dynamodb.AttributeValue{N: &sum}, nil
+			}
+		case UpdateAppend, UpdatePrepend:
+			fn = func(av *dynamodb.AttributeValue) (*dynamodb.AttributeValue, error) {
+				x, err := marshalUpdateValue(op.Value)
+				if err != nil || av == nil {
+					return x, err
+				}
+				if av.L == nil {
+					return nil, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("attribute %q is not a list", op.Attribute)}
+				}
+				if op.Operator == UpdateAppend {
+					return &dynamodb.AttributeValue{L: append(append([]*dynamodb.AttributeValue{}, av.L...), x.L...)}, nil
+				}
+				return &dynamodb.AttributeValue{L: append(append([]*dynamodb.AttributeValue{}, x.L...), av.L...)}, nil
+			}
+		case UpdateAddToSet, UpdateRemoveFromSet:
+			fn = func(av *dynamodb.AttributeValue) (*dynamodb.AttributeValue, error) {
+				set, err := setAttributeValue(op.Attribute, op.Value.([]interface{}))
+				if err != nil {
+					return nil, err
+				}
+				if av == nil {
+					if op.Operator == UpdateAddToSet {
+						return set, nil
+					}
+					return nil, nil
+				}
+				if (set.SS != nil && av.SS == nil) || (set.NS != nil && av.NS == nil) {
+					return nil, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("attribute %q is not a set of the same type", op.Attribute)}
+				}
+				if op.Operator == UpdateAddToSet {
+					return unionSets(av, set), nil
+				}
+				return differenceSets(av, set), nil
+			}
+		case UpdateSetIfNotExists:
+			fn = func(av *dynamodb.AttributeValue) (*dynamodb.AttributeValue, error) {
+				if av != nil {
+					return av, nil
+				}
+				return marshalUpdateValue(op.Value)
+			}
+		default:
+			return &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("unknown update operator %q", op.Operator)}
+		}
+		err := updateAttributeValueAtPath(item, strings.Split(op.Attribute, "."), fn)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func marshalUpdateValue(v interface{}) (*dynamodb.AttributeValue, error) {
+	av, err := dynamodbattribute.Marshal(v)
+	if err != nil {
+		return nil, &Error{Kind: ErrInvalidQuery, Err: err}
+	}
+	return av, nil
+}
+
+// updateAttributeValueAtPath replaces the attribute value at the path with the value
+// returned by fn, or removes the attribute if fn returns nil.
+func updateAttributeValueAtPath(item map[string]*dynamodb.AttributeValue, path []string, fn func(av *dynamodb.AttributeValue) (*dynamodb.AttributeValue, error)) error {
+	if len(path) == 1 {
+		av, err := fn(item[path[0]])
+		if err != nil {
+			return err
+		}
+		if av == nil {
+			delete(item, path[0])
+		} else {
+			item[path[0]] = av
+		}
+		return nil
+	}
+	parent := item[path[0]]
+	if parent == nil || parent.M == nil {
+		return &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("attribute %q is not a map", path[0])}
+	}
+	m := make(map[string]*dynamodb.AttributeValue, len(parent.M))
+	for k, v := range parent.M {
+		m[k] = v
+	}
+	err := updateAttributeValueAtPath(m, path[1:], fn)
+	if err != nil {
+		return err
+	}
+	item[path[0]] = &dynamodb.AttributeValue{M: m}
+	return nil
+}
+
+// unionSets returns the elements of a followed by the elements of b not in a.
+func unionSets(a *dynamodb.AttributeValue, b *dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	if a.SS != nil {
+		ss := append([]*string{}, a.SS...)
+		for _, x := range b.SS {
+			if indexSetString(a.SS, *x) == -1 {
+				ss = append(ss, x)
+			}
+		}
+		return &dynamodb.AttributeValue{SS: ss}
+	}
+	ns := append([]*string{}, a.NS...)
+	for _, x := range b.NS {
+		if indexSetNumber(a.NS, *x) == -1 {
+			ns = append(ns, x)
+		}
+	}
+	return &dynamodb.AttributeValue{NS: ns}
+}
+
+// differenceSets returns the elements of a not in b, or nil if there are none, since
+// DynamoDB removes empty sets.
+func differenceSets(a *dynamodb.AttributeValue, b *dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	if a.SS != nil {
+		ss := []*string{}
+		for _, x := range a.SS {
+			if indexSetString(b.SS, *x) == -1 {
+				ss = append(ss, x)
+			}
+		}
+		if len(ss) == 0 {
+			return nil
+		}
+		return &dynamodb.AttributeValue{SS: ss}
+	}
+	ns := []*string{}
+	for _, x := range a.NS {
+		if indexSetNumber(b.NS, *x) == -1 {
+			ns = append(ns, x)
+		}
+	}
+	if len(ns) == 0 {
+		return nil
+	}
+	return &dynamodb.AttributeValue{NS: ns}
+}
+
+func indexSetString(set []*string, s string) int {
+	for i, x := range set {
+		if *x == s {
+			return i
+		}
+	}
+	return -1
+}
+
+func indexSetNumber(set []*string, n string) int {
+	for i, x := range set {
+		if compareNumbers(*x, n) == 0 {
+			return i
+		}
+	}
+	return -1
+}
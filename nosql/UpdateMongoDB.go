@@ -0,0 +1,47 @@
+package nosql
+
+import (
+	"fmt"
+)
+
+import (
+	"gopkg.in/mgo.v2/bson"
+)
+
+// compileUpdateMongoDB compiles the operations of an update into MongoDB update
+// operators.  SetIfNotExists compiles to $setOnInsert, which only applies when the
+// update inserts the item, so UpdateItemContext also applies it to existing items.
+func compileUpdateMongoDB(operations []UpdateOperation) (bson.M, error) {
+	update := bson.M{}
+	operator := func(name string) bson.M {
+		if m, ok := update[name]; ok {
+			return m.(bson.M)
+		}
+		m := bson.M{}
+		update[name] = m
+		return m
+	}
+	for _, op := range operations {
+		switch op.Operator {
+		case UpdateSet:
+			operator("$set")[op.Attribute] = op.Value
+		case UpdateUnset:
+			operator("$unset")[op.Attribute] = ""
+		case UpdateIncrement:
+			operator("$inc")[op.Attribute] = op.Value
+		case UpdateAppend:
+			operator("$push")[op.Attribute] = bson.M{"$each": op.Value}
+		case UpdatePrepend:
+			operator("$push")[op.Attribute] = bson.M{"$each": op.Value, "$position": 0}
+		case UpdateAddToSet:
+			operator("$addToSet")[op.Attribute] = bson.M{"$each": op.Value}
+		case UpdateRemoveFromSet:
+			operator("$pull")[op.Attribute] = bson.M{"$in": op.Value}
+		case UpdateSetIfNotExists:
+			operator("$setOnInsert")[op.Attribute] = op.Value
+		default:
+			return nil, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("unknown update operator %q", op.Operator)}
+		}
+	}
+	return update, nil
+}
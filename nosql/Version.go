@@ -0,0 +1,56 @@
+package nosql
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// versionValue returns the version given as a value of any numeric type.  A nil
+// value is version 0, like a missing attribute.
+func versionValue(v interface{}) (int64, error) {
+	if v == nil {
+		return 0, nil
+	}
+	x := reflect.ValueOf(v)
+	switch x.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return x.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if x.Uint() <= math.MaxInt64 {
+			return int64(x.Uint()), nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if f := x.Float(); f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+			return int64(f), nil
+		}
+	}
+	return 0, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("version %v is not an integer", v)}
+}
+
+// versionAttributeValue returns the version stored in an attribute value.  A missing
+// attribute is version 0.
+func versionAttributeValue(av *dynamodb.AttributeValue) (int64, error) {
+	if av == nil || av.NULL != nil {
+		return 0, nil
+	}
+	if av.N == nil {
+		return 0, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("version %s is not a number", av.String())}
+	}
+	version, err := strconv.ParseInt(*av.N, 10, 64)
+	if err != nil {
+		return 0, &Error{Kind: ErrInvalidQuery, Err: fmt.Errorf("version %s is not an integer", *av.N)}
+	}
+	return version, nil
+}
+
+// versionAttribute returns the attribute value of a version.
+func versionAttribute(version int64) *dynamodb.AttributeValue {
+	return &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(version, 10))}
+}
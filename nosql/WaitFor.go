@@ -0,0 +1,33 @@
+package nosql
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// defaultWaitTimeout is the time to wait for a table to become active or be deleted.
+const defaultWaitTimeout = 5 * time.Minute
+
+// waitFor calls done with backoff until it returns true, the timeout passes, or ctx is
+// done.  The error of a timeout wraps context.DeadlineExceeded and names what was
+// awaited.
+func waitFor(ctx context.Context, timeout time.Duration, what string, done func(ctx context.Context) (bool, error)) error {
+	wait_ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for attempt := 0; ; attempt++ {
+		ok, err := done(wait_ctx)
+		if err == nil && ok {
+			return nil
+		}
+		if err == nil {
+			err = sleepContext(wait_ctx, backoff(attempt))
+		}
+		if err != nil {
+			if ctx.Err() == nil && wait_ctx.Err() != nil {
+				return fmt.Errorf("timed out after %s waiting for %s: %w", timeout, what, context.DeadlineExceeded)
+			}
+			return err
+		}
+	}
+}
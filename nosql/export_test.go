@@ -0,0 +1,21 @@
+package nosql
+
+import (
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// The filter compilers are exported to the tests of package nosql_test, which can
+// import the parser.
+
+func CompileFilterDynamoDB(f Filter) (string, error) {
+	return newExpressionDynamoDB().filter(f)
+}
+
+func CompileFilterMongoDB(f Filter) error {
+	_, err := compileFilterMongoDB(f)
+	return err
+}
+
+func MatchFilterMemory(item map[string]*dynamodb.AttributeValue, f Filter) (bool, error) {
+	return matchFilterMemory(item, f)
+}
@@ -0,0 +1,32 @@
+package nosqltest
+
+import (
+	"github.com/spatialcurrent/go-nosql/nosql"
+)
+
+// Event is the fixture stored by the conformance suite in tables with a composite key,
+// partitioned by user and sorted by time.
+type Event struct {
+	User string `json:"user" bson:"user"`
+	Time int    `json:"time" bson:"time"`
+	Kind string `json:"kind" bson:"kind"`
+}
+
+// eventsTable returns the key schema of the tables of events.
+func eventsTable() nosql.Table {
+	return nosql.Table{
+		PartitionKey: nosql.Attribute{Name: "user", Type: nosql.AttributeTypeString},
+		SortKey:      nosql.Attribute{Name: "time", Type: nosql.AttributeTypeNumber},
+	}
+}
+
+// events returns a fresh copy of the events, sorted by user and time.
+func events() []Event {
+	return []Event{
+		Event{User: "u1", Time: 1, Kind: "login"},
+		Event{User: "u1", Time: 2, Kind: "view"},
+		Event{User: "u1", Time: 10, Kind: "view"},
+		Event{User: "u1", Time: 20, Kind: "logout"},
+		Event{User: "u2", Time: 2, Kind: "login"},
+	}
+}
@@ -0,0 +1,41 @@
+package nosqltest
+
+import (
+	"time"
+)
+
+// Item is the fixture stored by the conformance suite.  The id is stored as "id" by
+// backends that marshal with json tags and as "_id" by MongoDB.
+type Item struct {
+	Id     string   `json:"id" bson:"_id"`
+	Name   string   `json:"name" bson:"name"`
+	Status string   `json:"status" bson:"status"`
+	Rank   int      `json:"rank" bson:"rank"`
+	Tags   []string `json:"tags" bson:"tags"`
+	// Labels is only set by updates, and is stored as a set by DynamoDB.
+	Labels []string `json:"labels,omitempty" bson:"labels,omitempty"`
+	// Version is only set in versioned tables.
+	Version int64 `json:"version,omitempty" bson:"version,omitempty"`
+	// Expires is only set in tables with a time to live attribute.
+	Expires *time.Time `json:"expires,omitempty" bson:"expires,omitempty" dynamodbav:"expires,omitempty"`
+}
+
+// items returns a fresh copy of the fixtures, sorted by rank.
+func items() []Item {
+	return []Item{
+		Item{Id: "a", Name: "alpha", Status: "active", Rank: 1, Tags: []string{"x", "y"}},
+		Item{Id: "b", Name: "beta", Status: "active", Rank: 2, Tags: []string{"y"}},
+		Item{Id: "c", Name: "gamma", Status: "inactive", Rank: 3, Tags: []string{"z"}},
+		Item{Id: "d", Name: "delta", Status: "inactive", Rank: 4, Tags: []string{"x"}},
+		Item{Id: "e", Name: "epsilon", Status: "pending", Rank: 5, Tags: []string{"z"}},
+	}
+}
+
+// ids returns the ids of the items in order.
+func ids(items []Item) []string {
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.Id)
+	}
+	return ids
+}
@@ -0,0 +1,1125 @@
+package nosqltest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/spatialcurrent/go-nosql/nosql"
+)
+
+// conformanceCase is a test run against a table containing the fixtures.
+type conformanceCase struct {
+	name string
+	run  func(t *testing.T, b nosql.Backend, table_name string)
+}
+
+var tableCounter int64
+
+// RunConformance runs the conformance suite against the backends returned by
+// newBackend, which is called once for every test and must return a connected
+// backend.  Every test uses its own table with an index on "status", which is
+// deleted when the test completes.
+//
+//	func TestConformance(t *testing.T) {
+//		nosqltest.RunConformance(t, func() nosql.Backend {
+//			return &nosql.BackendMemory{}
+//		})
+//	}
+func RunConformance(t *testing.T, newBackend func() nosql.Backend) {
+	for _, c := range conformanceCases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			b := newBackend()
+			table_name := createTable(t, b, nosql.Table{Indexes: []string{"status"}})
+			c.run(t, b, table_name)
+		})
+	}
+}
+
+// newTableName returns a table name that is unique within the process and unlikely to
+// collide with the tables of other processes.
+func newTableName() string {
+	return "nosqltest_" + strconv.FormatInt(time.Now().UnixNano(), 36) + "_" + strconv.FormatInt(atomic.AddInt64(&tableCounter, 1), 10)
+}
+
+// createTable creates a new table like the given table with the fixtures, which is
+// deleted when the test completes.
+func createTable(t *testing.T, b nosql.Backend, table nosql.Table) string {
+	t.Helper()
+	table_name := createEmptyTable(t, b, table)
+	for _, item := range items() {
+		err := b.InsertItem(table_name, item)
+		if err != nil {
+			t.Fatalf("InsertItem(%q) returned error: %v", item.Id, err)
+		}
+	}
+	return table_name
+}
+
+// createEmptyTable creates a new table like the given table, which is deleted when the
+// test completes.
+func createEmptyTable(t *testing.T, b nosql.Backend, table nosql.Table) string {
+	t.Helper()
+	table_name := newTableName()
+	table.Name = table_name
+	table.ReadUnits = 1
+	table.WriteUnits = 1
+	err := b.CreateTables([]nosql.Table{table})
+	if err != nil {
+		t.Fatalf("CreateTables(%q) returned error: %v", table_name, err)
+	}
+	t.Cleanup(func() {
+		err := b.DeleteTables([]string{table_name})
+		if err != nil {
+			t.Errorf("DeleteTables(%q) returned error: %v", table_name, err)
+		}
+	})
+	return table_name
+}
+
+// createEventsTable creates a new table of events with a composite key and a local
+// index on the kind, which is deleted when the test completes.
+func createEventsTable(t *testing.T, b nosql.Backend) string {
+	t.Helper()
+	table := eventsTable()
+	table.SecondaryIndexes = []nosql.Index{{RangeKey: "kind", Local: true}}
+	table_name := createEmptyTable(t, b, table)
+	for _, e := range events() {
+		err := b.InsertItem(table_name, e)
+		if err != nil {
+			t.Fatalf("InsertItem(%q, %d) returned error: %v", e.User, e.Time, err)
+		}
+	}
+	return table_name
+}
+
+// expectEvents checks all the events of a table, sorted by user and time.
+func expectEvents(t *testing.T, b nosql.Backend, table_name string, name string, expected ...Event) {
+	t.Helper()
+	results := []Event{}
+	expectNoError(t, "Find", b.Find(table_name, nil, &nosql.FindOptions{SortFields: []string{"user", "time"}}, &results))
+	if len(results) != len(expected) || (len(expected) > 0 && !reflect.DeepEqual(results, expected)) {
+		t.Fatalf("%s left %+v, expecting %+v", name, results, expected)
+	}
+}
+
+func expectError(t *testing.T, name string, err error, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Fatalf("%s returned error %v, expecting %v", name, err, target)
+	}
+}
+
+func expectNoError(t *testing.T, name string, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s returned error: %v", name, err)
+	}
+}
+
+// expectIds checks the ids of the items in order.
+func expectIds(t *testing.T, name string, items []Item, expected ...string) {
+	t.Helper()
+	got := ids(items)
+	if !reflect.DeepEqual(got, append([]string{}, expected...)) {
+		t.Fatalf("%s returned ids %v, expecting %v", name, got, expected)
+	}
+}
+
+// expectIdSet checks the ids of the items in any order.
+func expectIdSet(t *testing.T, name string, items []Item, expected ...string) {
+	t.Helper()
+	got := ids(items)
+	sort.Strings(got)
+	want := append([]string{}, expected...)
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("%s returned ids %v, expecting %v in any order", name, got, want)
+	}
+}
+
+func expectItem(t *testing.T, name string, got Item, expected Item) {
+	t.Helper()
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("%s returned %+v, expecting %+v", name, got, expected)
+	}
+}
+
+// skipNotSupported skips the test if the backend does not support the feature.
+func skipNotSupported(t *testing.T, err error) {
+	t.Helper()
+	if errors.Is(err, nosql.ErrNotSupported) {
+		t.Skipf("not supported: %v", err)
+	}
+}
+
+// findIndex returns the index with the given name.
+func findIndex(indexes []nosql.Index, index_name string) (nosql.Index, bool) {
+	for _, index := range indexes {
+		if index.Name == index_name {
+			return index, true
+		}
+	}
+	return nosql.Index{}, false
+}
+
+// expectSchemaChanges checks the types of the changes of a plan in order.
+func expectSchemaChanges(t *testing.T, name string, plan *nosql.SchemaPlan, expected ...nosql.SchemaChangeType) {
+	t.Helper()
+	got := []nosql.SchemaChangeType{}
+	for _, c := range plan.Changes {
+		got = append(got, c.Type)
+	}
+	if !reflect.DeepEqual(got, append([]nosql.SchemaChangeType{}, expected...)) {
+		t.Fatalf("%s returned plan:\n%s\nexpecting changes %v", name, plan, expected)
+	}
+}
+
+// expectBulkResults checks that the results of a bulk write succeeded for the ids in
+// order.
+func expectBulkResults(t *testing.T, name string, results []nosql.BulkResult, expected ...string) {
+	t.Helper()
+	if len(results) != len(expected) {
+		t.Fatalf("%s returned %d results, expecting %d", name, len(results), len(expected))
+	}
+	for i, r := range results {
+		if r.Index != i || r.Id != expected[i] || r.Err != nil {
+			t.Fatalf("%s returned result %+v, expecting index %d and id %q without error", name, r, i, expected[i])
+		}
+	}
+}
+
+// findCases are the filters of the Find test and the ids of the fixtures they match.
+var findCases = []struct {
+	name   string
+	filter nosql.Filter
+	ids    []string
+}{
+	{"Nil", nil, []string{"a", "b", "c", "d", "e"}},
+	{"Eq", nosql.Eq("status", "active"), []string{"a", "b"}},
+	{"EqNumber", nosql.Eq("rank", 3), []string{"c"}},
+	{"EqId", nosql.Eq("id", "c"), []string{"c"}},
+	{"InId", nosql.In("id", "a", "e", "z"), []string{"a", "e"}},
+	{"Ne", nosql.Ne("status", "active"), []string{"c", "d", "e"}},
+	{"Lt", nosql.Lt("rank", 3), []string{"a", "b"}},
+	{"Lte", nosql.Lte("rank", 3), []string{"a", "b", "c"}},
+	{"Gt", nosql.Gt("rank", 3), []string{"d", "e"}},
+	{"Gte", nosql.Gte("rank", 3), []string{"c", "d", "e"}},
+	{"In", nosql.In("status", "inactive", "pending"), []string{"c", "d", "e"}},
+	{"Between", nosql.Between("rank", 2, 4), []string{"b", "c", "d"}},
+	{"BeginsWith", nosql.BeginsWith("name", "de"), []string{"d"}},
+	{"ContainsString", nosql.Contains("name", "lph"), []string{"a"}},
+	{"ContainsElement", nosql.Contains("tags", "x"), []string{"a", "d"}},
+	{"Exists", nosql.Exists("name"), []string{"a", "b", "c", "d", "e"}},
+	{"NotExists", nosql.Not(nosql.Exists("missing")), []string{"a", "b", "c", "d", "e"}},
+	{"And", nosql.And(nosql.Eq("status", "inactive"), nosql.Gt("rank", 3)), []string{"d"}},
+	{"Or", nosql.Or(nosql.Eq("status", "pending"), nosql.Lt("rank", 2)), []string{"a", "e"}},
+	{"Not", nosql.Not(nosql.Eq("status", "active")), []string{"c", "d", "e"}},
+	{"NoMatch", nosql.Eq("status", "missing"), []string{}},
+}
+
+var conformanceCases = []conformanceCase{
+	{"CreateTableExists", func(t *testing.T, b nosql.Backend, table_name string) {
+		err := b.CreateTable(table_name, []string{"status"}, 1, 1)
+		expectError(t, "CreateTable", err, nosql.ErrTableExists)
+	}},
+	{"CreateAndDeleteTable", func(t *testing.T, b nosql.Backend, table_name string) {
+		other := newTableName()
+		expectNoError(t, "CreateTable", b.CreateTable(other, []string{}, 1, 1))
+		expectNoError(t, "DeleteTable", b.DeleteTable(other))
+		expectError(t, "DeleteTable", b.DeleteTable(other), nosql.ErrTableNotFound)
+	}},
+	{"DeleteTableNotFound", func(t *testing.T, b nosql.Backend, table_name string) {
+		expectError(t, "DeleteTable", b.DeleteTable(newTableName()), nosql.ErrTableNotFound)
+	}},
+	{"DeleteTablesNotFound", func(t *testing.T, b nosql.Backend, table_name string) {
+		expectNoError(t, "DeleteTables", b.DeleteTables([]string{newTableName()}))
+	}},
+	{"ListTables", func(t *testing.T, b nosql.Backend, table_name string) {
+		table_names, err := b.ListTables()
+		expectNoError(t, "ListTables", err)
+		found := false
+		for _, name := range table_names {
+			found = found || name == table_name
+		}
+		if !found {
+			t.Fatalf("ListTables returned %v, expecting %q", table_names, table_name)
+		}
+		exists, err := b.TableExists(table_name)
+		expectNoError(t, "TableExists", err)
+		if !exists {
+			t.Fatalf("TableExists(%q) returned false", table_name)
+		}
+		exists, err = b.TableExists(newTableName())
+		expectNoError(t, "TableExists", err)
+		if exists {
+			t.Fatalf("TableExists returned true for a missing table")
+		}
+	}},
+	{"DescribeTable", func(t *testing.T, b nosql.Backend, table_name string) {
+		description, err := b.DescribeTable(table_name)
+		expectNoError(t, "DescribeTable", err)
+		if description.Name != table_name || description.PartitionKey.Name != "id" || len(description.SortKey.Name) > 0 || description.Status != nosql.TableStatusActive {
+			t.Fatalf("DescribeTable returned %+v, expecting an active table keyed by id", description)
+		}
+		if index, ok := findIndex(description.SecondaryIndexes, "status-index"); !ok || index.HashKey != "status" {
+			t.Fatalf("DescribeTable returned indexes %+v, expecting status-index", description.SecondaryIndexes)
+		}
+		events_table := createEventsTable(t, b)
+		description, err = b.DescribeTable(events_table)
+		expectNoError(t, "DescribeTable", err)
+		if description.PartitionKey != (nosql.Attribute{Name: "user", Type: nosql.AttributeTypeString}) || description.SortKey != (nosql.Attribute{Name: "time", Type: nosql.AttributeTypeNumber}) {
+			t.Fatalf("DescribeTable returned keys %+v and %+v, expecting user and time", description.PartitionKey, description.SortKey)
+		}
+		if index, ok := findIndex(description.SecondaryIndexes, "kind-index"); !ok || index.HashKey != "user" || index.RangeKey != "kind" {
+			t.Fatalf("DescribeTable returned indexes %+v, expecting kind-index", description.SecondaryIndexes)
+		}
+		_, err = b.DescribeTable(newTableName())
+		expectError(t, "DescribeTable", err, nosql.ErrTableNotFound)
+	}},
+	{"GetItemById", func(t *testing.T, b nosql.Backend, table_name string) {
+		item := Item{}
+		expectNoError(t, "GetItemById", b.GetItemById(table_name, "a", &item))
+		expectItem(t, "GetItemById", item, items()[0])
+	}},
+	{"GetItemByIdNotFound", func(t *testing.T, b nosql.Backend, table_name string) {
+		item := Item{}
+		expectError(t, "GetItemById", b.GetItemById(table_name, "z", &item), nosql.ErrNotFound)
+	}},
+	{"GetItem", func(t *testing.T, b nosql.Backend, table_name string) {
+		item := Item{}
+		expectNoError(t, "GetItem", b.GetItem(table_name, nosql.Key{"id": "c"}, &item))
+		expectItem(t, "GetItem", item, items()[2])
+		expectError(t, "GetItem", b.GetItem(table_name, nosql.Key{"id": "z"}, &item), nosql.ErrNotFound)
+		expectError(t, "GetItem", b.GetItem(table_name, nosql.Key{"name": "gamma"}, &item), nosql.ErrInvalidQuery)
+	}},
+	{"CompositeKey", func(t *testing.T, b nosql.Backend, table_name string) {
+		events_table := createEventsTable(t, b)
+		e := Event{}
+		expectNoError(t, "GetItem", b.GetItem(events_table, nosql.Key{"user": "u2", "time": 2}, &e))
+		if e != events()[4] {
+			t.Fatalf("GetItem returned %+v, expecting %+v", e, events()[4])
+		}
+		expectError(t, "GetItem", b.GetItem(events_table, nosql.Key{"user": "u2", "time": 3}, &e), nosql.ErrNotFound)
+		expectError(t, "GetItem", b.GetItem(events_table, nosql.Key{"user": "u2"}, &e), nosql.ErrInvalidQuery)
+		expectNoError(t, "RemoveItem", b.RemoveItem(events_table, nosql.Key{"user": "u2", "time": 2}))
+		expectError(t, "GetItem", b.GetItem(events_table, nosql.Key{"user": "u2", "time": 2}, &e), nosql.ErrNotFound)
+		expectNoError(t, "GetItem", b.GetItem(events_table, nosql.Key{"user": "u1", "time": 2}, &e))
+	}},
+	{"QueryItems", func(t *testing.T, b nosql.Backend, table_name string) {
+		events_table := createEventsTable(t, b)
+		queries := []struct {
+			query    *nosql.KeyQuery
+			expected []int
+		}{
+			{&nosql.KeyQuery{Partition: "u1"}, []int{1, 2, 10, 20}},
+			{&nosql.KeyQuery{Partition: "u1", Descending: true, Limit: 3}, []int{20, 10, 2}},
+			{&nosql.KeyQuery{Partition: "u1", Sort: nosql.Between("time", 2, 10)}, []int{2, 10}},
+			{&nosql.KeyQuery{Partition: "u1", Sort: nosql.Gt("time", 2), Descending: true}, []int{20, 10}},
+			{&nosql.KeyQuery{Partition: "u2", Sort: nosql.Eq("time", 2)}, []int{2}},
+			{&nosql.KeyQuery{Partition: "u3"}, []int{}},
+		}
+		for _, q := range queries {
+			results := []Event{}
+			expectNoError(t, "QueryItems", b.QueryItems(events_table, q.query, &results))
+			times := make([]int, 0, len(results))
+			for _, e := range results {
+				times = append(times, e.Time)
+			}
+			if !reflect.DeepEqual(times, q.expected) {
+				t.Fatalf("QueryItems(%+v) returned times %v, expecting %v", q.query, times, q.expected)
+			}
+		}
+		results := []Event{}
+		expectError(t, "QueryItems", b.QueryItems(events_table, &nosql.KeyQuery{Partition: "u1", Sort: nosql.Ne("time", 2)}, &results), nosql.ErrInvalidQuery)
+		expectError(t, "QueryItems", b.QueryItems(events_table, &nosql.KeyQuery{Partition: "u1", Sort: nosql.Gt("kind", "a")}, &results), nosql.ErrInvalidQuery)
+		expectError(t, "QueryItems", b.QueryItems(events_table, &nosql.KeyQuery{}, &results), nosql.ErrInvalidQuery)
+	}},
+	{"QueryItemsIndex", func(t *testing.T, b nosql.Backend, table_name string) {
+		indexed := createTable(t, b, nosql.Table{
+			Indexes:          []string{"status"},
+			Attributes:       []nosql.Attribute{{Name: "rank", Type: nosql.AttributeTypeNumber}},
+			SecondaryIndexes: []nosql.Index{{Name: "status-rank-index", HashKey: "status", RangeKey: "rank", Projection: nosql.ProjectionKeysOnly}},
+		})
+		results := []Item{}
+		expectNoError(t, "QueryItems", b.QueryItems(indexed, &nosql.KeyQuery{Index: "status-rank-index", Partition: "inactive", Descending: true}, &results))
+		expectIds(t, "QueryItems", results, "d", "c")
+		results = []Item{}
+		expectNoError(t, "QueryItems", b.QueryItems(indexed, &nosql.KeyQuery{Index: "status-rank-index", Partition: "active", Sort: nosql.Gte("rank", 2)}, &results))
+		expectIds(t, "QueryItems", results, "b")
+		results = []Item{}
+		expectNoError(t, "GetItemsByAttributeValue", b.GetItemsByAttributeValue(indexed, "status", "pending", []string{}, &results))
+		expectIds(t, "GetItemsByAttributeValue", results, "e")
+		expectError(t, "QueryItems", b.QueryItems(indexed, &nosql.KeyQuery{Index: "missing-index", Partition: "active"}, &results), nosql.ErrInvalidQuery)
+	}},
+	{"QueryItemsLocalIndex", func(t *testing.T, b nosql.Backend, table_name string) {
+		events_table := createEventsTable(t, b)
+		results := []Event{}
+		expectNoError(t, "QueryItems", b.QueryItems(events_table, &nosql.KeyQuery{Index: "kind-index", Partition: "u1", Sort: nosql.BeginsWith("kind", "log")}, &results))
+		if len(results) != 2 || results[0].Time != 1 || results[1].Time != 20 {
+			t.Fatalf("QueryItems returned %+v, expecting the login and logout of u1", results)
+		}
+	}},
+	{"CreateTableInvalidIndexes", func(t *testing.T, b nosql.Backend, table_name string) {
+		invalid := []nosql.Index{
+			{Name: "local-index", RangeKey: "rank", HashKey: "status", Local: true},
+			{Name: "local-index", Local: true},
+			{Name: "include-index", HashKey: "status", Projection: nosql.ProjectionInclude},
+			{Name: "keys-index", HashKey: "status", Projection: nosql.ProjectionKeysOnly, ProjectionAttributes: []string{"name"}},
+		}
+		for _, index := range invalid {
+			err := b.CreateTables([]nosql.Table{{Name: newTableName(), ReadUnits: 1, WriteUnits: 1, SecondaryIndexes: []nosql.Index{index}}})
+			expectError(t, "CreateTables", err, nosql.ErrInvalidQuery)
+		}
+	}},
+	{"Count", func(t *testing.T, b nosql.Backend, table_name string) {
+		for _, c := range []struct {
+			filter   nosql.Filter
+			expected int64
+		}{
+			{nil, 5},
+			{nosql.Eq("status", "active"), 2},
+			{nosql.And(nosql.Eq("status", "inactive"), nosql.Gt("rank", 3)), 1},
+			{nosql.Eq("status", "missing"), 0},
+		} {
+			count, err := b.Count(table_name, c.filter)
+			expectNoError(t, "Count", err)
+			if count != c.expected {
+				t.Fatalf("Count(%v) returned %d, expecting %d", c.filter, count, c.expected)
+			}
+		}
+	}},
+	{"Exists", func(t *testing.T, b nosql.Backend, table_name string) {
+		exists, err := b.Exists(table_name, "c")
+		expectNoError(t, "Exists", err)
+		if !exists {
+			t.Fatalf("Exists(%q) returned false, expecting true", "c")
+		}
+		exists, err = b.Exists(table_name, "z")
+		expectNoError(t, "Exists", err)
+		if exists {
+			t.Fatalf("Exists(%q) returned true, expecting false", "z")
+		}
+	}},
+	{"UpdateThroughput", func(t *testing.T, b nosql.Backend, table_name string) {
+		throughput := &nosql.Throughput{ReadUnits: 2, WriteUnits: 2, Indexes: map[string]nosql.Throughput{"status-index": {ReadUnits: 3, WriteUnits: 3}}}
+		expectNoError(t, "UpdateThroughput", b.UpdateThroughput(table_name, throughput))
+		description, err := b.DescribeTable(table_name)
+		expectNoError(t, "DescribeTable", err)
+		// Backends without throughput describe no billing mode.
+		if len(description.BillingMode) > 0 {
+			index, _ := findIndex(description.SecondaryIndexes, "status-index")
+			if description.BillingMode != nosql.BillingModeProvisioned || description.ReadUnits != 2 || description.WriteUnits != 2 || index.ReadUnits != 3 || index.WriteUnits != 3 {
+				t.Fatalf("DescribeTable returned %+v, expecting 2 units for the table and 3 for status-index", description)
+			}
+		}
+		expectNoError(t, "UpdateThroughput", b.UpdateThroughput(table_name, &nosql.Throughput{BillingMode: nosql.BillingModePayPerRequest}))
+		description, err = b.DescribeTable(table_name)
+		expectNoError(t, "DescribeTable", err)
+		if len(description.BillingMode) > 0 && description.BillingMode != nosql.BillingModePayPerRequest {
+			t.Fatalf("DescribeTable returned billing mode %q, expecting %q", description.BillingMode, nosql.BillingModePayPerRequest)
+		}
+		expectError(t, "UpdateThroughput", b.UpdateThroughput(table_name, &nosql.Throughput{BillingMode: nosql.BillingModeProvisioned}), nosql.ErrInvalidQuery)
+		expectError(t, "UpdateThroughput", b.UpdateThroughput(newTableName(), throughput), nosql.ErrTableNotFound)
+	}},
+	{"TimeToLive", func(t *testing.T, b nosql.Backend, table_name string) {
+		ttl_table := createEmptyTable(t, b, nosql.Table{TTLAttribute: "expires"})
+		description, err := b.DescribeTable(ttl_table)
+		expectNoError(t, "DescribeTable", err)
+		if description.TTLAttribute != "expires" {
+			t.Fatalf("DescribeTable returned time to live attribute %q, expecting %q", description.TTLAttribute, "expires")
+		}
+		future := time.Now().Add(time.Hour)
+		past := time.Now().Add(-1 * time.Hour)
+		expectNoError(t, "InsertItem", b.InsertItem(ttl_table, Item{Id: "a", Name: "alpha", Expires: &future}))
+		expectNoError(t, "InsertItem", b.InsertItem(ttl_table, Item{Id: "b", Name: "beta", Expires: &past}))
+		expectNoError(t, "InsertItem", b.InsertItem(ttl_table, Item{Id: "c", Name: "gamma"}))
+		expectNoError(t, "InsertItem", b.InsertItem(ttl_table, Item{Id: "d", Name: "delta", Expires: &future}))
+		expectNoError(t, "UpdateItemById", b.UpdateItemById(ttl_table, "d", map[string]interface{}{"expires": past}))
+		item := Item{}
+		expectNoError(t, "GetItemById", b.GetItemById(ttl_table, "a", &item))
+		if item.Expires == nil || !item.Expires.Truncate(time.Second).Equal(future.Truncate(time.Second)) {
+			t.Fatalf("GetItemById returned expiry %v, expecting %v", item.Expires, future)
+		}
+		expectError(t, "GetItemById", b.GetItemById(ttl_table, "b", &item), nosql.ErrNotFound)
+		expectError(t, "GetItemById", b.GetItemById(ttl_table, "d", &item), nosql.ErrNotFound)
+		results := []Item{}
+		expectNoError(t, "GetItems", b.GetItems(ttl_table, "", nil, &results))
+		expectIdSet(t, "GetItems", results, "a", "c")
+		exists, err := b.Exists(ttl_table, "b")
+		expectNoError(t, "Exists", err)
+		if exists {
+			t.Fatalf("Exists returned true for an expired item")
+		}
+		count, err := b.Count(ttl_table, nil)
+		expectNoError(t, "Count", err)
+		if count != 2 {
+			t.Fatalf("Count returned %d, expecting 2", count)
+		}
+
+		expectNoError(t, "UpdateTimeToLive", b.UpdateTimeToLive(ttl_table, ""))
+		description, err = b.DescribeTable(ttl_table)
+		expectNoError(t, "DescribeTable", err)
+		if len(description.TTLAttribute) > 0 {
+			t.Fatalf("DescribeTable returned time to live attribute %q, expecting none", description.TTLAttribute)
+		}
+		plan, err := nosql.Plan(b, []nosql.Table{{Name: ttl_table, TTLAttribute: "expires", ReadUnits: 1, WriteUnits: 1}})
+		skipNotSupported(t, err)
+		expectNoError(t, "Plan", err)
+		expectSchemaChanges(t, "Plan", plan, nosql.SchemaUpdateTimeToLive)
+	}},
+	{"SyncBillingMode", func(t *testing.T, b nosql.Backend, table_name string) {
+		desired := nosql.Table{Name: newTableName(), Indexes: []string{"status"}}
+		_, err := nosql.Sync(b, []nosql.Table{desired})
+		skipNotSupported(t, err)
+		expectNoError(t, "Sync", err)
+		t.Cleanup(func() {
+			err := b.DeleteTables([]string{desired.Name})
+			if err != nil {
+				t.Errorf("DeleteTables(%q) returned error: %v", desired.Name, err)
+			}
+		})
+		description, err := b.DescribeTable(desired.Name)
+		expectNoError(t, "DescribeTable", err)
+		if len(description.BillingMode) == 0 {
+			return
+		}
+		if description.BillingMode != nosql.BillingModePayPerRequest {
+			t.Fatalf("DescribeTable returned billing mode %q, expecting %q for a table without units", description.BillingMode, nosql.BillingModePayPerRequest)
+		}
+		plan, err := nosql.Plan(b, []nosql.Table{desired})
+		expectNoError(t, "Plan", err)
+		expectSchemaChanges(t, "Plan", plan)
+		desired.ReadUnits = 1
+		desired.WriteUnits = 1
+		plan, err = nosql.Sync(b, []nosql.Table{desired})
+		expectNoError(t, "Sync", err)
+		expectSchemaChanges(t, "Sync", plan, nosql.SchemaUpdateThroughput)
+		desired.SecondaryIndexes = []nosql.Index{{HashKey: "status", ReadUnits: 2, WriteUnits: 2}}
+		plan, err = nosql.Sync(b, []nosql.Table{desired})
+		expectNoError(t, "Sync", err)
+		expectSchemaChanges(t, "Sync", plan, nosql.SchemaUpdateThroughput)
+		plan, err = nosql.Plan(b, []nosql.Table{desired})
+		expectNoError(t, "Plan", err)
+		expectSchemaChanges(t, "Plan", plan)
+	}},
+	{"Sync", func(t *testing.T, b nosql.Backend, table_name string) {
+		desired := nosql.Table{Name: newTableName(), Indexes: []string{"status"}, ReadUnits: 1, WriteUnits: 1}
+		plan, err := nosql.Plan(b, []nosql.Table{desired})
+		skipNotSupported(t, err)
+		expectNoError(t, "Plan", err)
+		expectSchemaChanges(t, "Plan", plan, nosql.SchemaCreateTable)
+		_, err = nosql.Sync(b, []nosql.Table{desired})
+		expectNoError(t, "Sync", err)
+		t.Cleanup(func() {
+			err := b.DeleteTables([]string{desired.Name})
+			if err != nil {
+				t.Errorf("DeleteTables(%q) returned error: %v", desired.Name, err)
+			}
+		})
+		plan, err = nosql.Plan(b, []nosql.Table{desired})
+		expectNoError(t, "Plan", err)
+		expectSchemaChanges(t, "Plan", plan)
+
+		desired.Indexes = []string{"name"}
+		desired.Attributes = []nosql.Attribute{{Name: "rank", Type: nosql.AttributeTypeNumber}}
+		desired.SecondaryIndexes = []nosql.Index{{Name: "status-rank-index", HashKey: "status", RangeKey: "rank"}}
+		plan, err = nosql.Sync(b, []nosql.Table{desired})
+		expectNoError(t, "Sync", err)
+		expectSchemaChanges(t, "Sync", plan, nosql.SchemaDeleteIndex, nosql.SchemaAddIndex, nosql.SchemaAddIndex)
+		plan, err = nosql.Plan(b, []nosql.Table{desired})
+		expectNoError(t, "Plan", err)
+		expectSchemaChanges(t, "Plan", plan)
+
+		for _, item := range items() {
+			expectNoError(t, "InsertItem", b.InsertItem(desired.Name, item))
+		}
+		results := []Item{}
+		expectNoError(t, "QueryItems", b.QueryItems(desired.Name, &nosql.KeyQuery{Index: "status-rank-index", Partition: "inactive"}, &results))
+		expectIds(t, "QueryItems", results, "c", "d")
+	}},
+	{"SyncInvalid", func(t *testing.T, b nosql.Backend, table_name string) {
+		_, err := nosql.Plan(b, []nosql.Table{{Name: table_name, Indexes: []string{"status"}, ReadUnits: 1, WriteUnits: 1}})
+		skipNotSupported(t, err)
+		expectNoError(t, "Plan", err)
+		_, err = nosql.Sync(b, []nosql.Table{{Name: table_name, PartitionKey: nosql.Attribute{Name: "name"}, ReadUnits: 1, WriteUnits: 1}})
+		expectError(t, "Sync", err, nosql.ErrNotSupported)
+		_, err = nosql.Sync(b, []nosql.Table{{Name: table_name, ReadUnits: 1, WriteUnits: 1, SecondaryIndexes: []nosql.Index{{Name: "include-index", HashKey: "status", Projection: nosql.ProjectionInclude}}}})
+		expectError(t, "Sync", err, nosql.ErrInvalidQuery)
+	}},
+	{"GetItemsByIds", func(t *testing.T, b nosql.Backend, table_name string) {
+		results := []Item{}
+		expectNoError(t, "GetItemsByIds", b.GetItemsByIds(table_name, []string{"c", "a", "d", "a"}, []string{}, &results))
+		expectIds(t, "GetItemsByIds", results, "c", "a", "d")
+		results = []Item{}
+		expectNoError(t, "GetItemsByIds", b.GetItemsByIds(table_name, []string{"c", "a", "d"}, []string{"-rank"}, &results))
+		expectIds(t, "GetItemsByIds", results, "d", "c", "a")
+	}},
+	{"GetItemsByIdsMissing", func(t *testing.T, b nosql.Backend, table_name string) {
+		results := []Item{}
+		err := b.GetItemsByIds(table_name, []string{"y", "b", "z"}, []string{}, &results)
+		expectError(t, "GetItemsByIds", err, nosql.ErrNotFound)
+		var missing *nosql.MissingItemsError
+		if !errors.As(err, &missing) || !reflect.DeepEqual(missing.Ids, []string{"y", "z"}) {
+			t.Fatalf("GetItemsByIds returned error %v, expecting missing ids [y z]", err)
+		}
+		expectIds(t, "GetItemsByIds", results, "b")
+	}},
+	{"GetItemByAttributeValue", func(t *testing.T, b nosql.Backend, table_name string) {
+		item := Item{}
+		expectNoError(t, "GetItemByAttributeValue", b.GetItemByAttributeValue(table_name, "status", "pending", &item))
+		expectItem(t, "GetItemByAttributeValue", item, items()[4])
+	}},
+	{"GetItemByAttributeValueNotFound", func(t *testing.T, b nosql.Backend, table_name string) {
+		item := Item{}
+		err := b.GetItemByAttributeValue(table_name, "status", "missing", &item)
+		expectError(t, "GetItemByAttributeValue", err, nosql.ErrNotFound)
+	}},
+	{"GetItemsByAttributeValue", func(t *testing.T, b nosql.Backend, table_name string) {
+		results := []Item{}
+		expectNoError(t, "GetItemsByAttributeValue", b.GetItemsByAttributeValue(table_name, "status", "inactive", []string{"rank"}, &results))
+		expectIds(t, "GetItemsByAttributeValue", results, "c", "d")
+		results = []Item{}
+		expectNoError(t, "GetItemsByAttributeValue", b.GetItemsByAttributeValue(table_name, "status", "inactive", []string{"-rank"}, &results))
+		expectIds(t, "GetItemsByAttributeValue", results, "d", "c")
+	}},
+	{"GetItemsByAttribute", func(t *testing.T, b nosql.Backend, table_name string) {
+		typed := createTable(t, b, nosql.Table{
+			Indexes:    []string{"status", "rank"},
+			Attributes: []nosql.Attribute{{Name: "rank", Type: nosql.AttributeTypeNumber}},
+		})
+		item := Item{}
+		expectNoError(t, "GetItemByAttribute", b.GetItemByAttribute(typed, "rank", 3, &item))
+		expectItem(t, "GetItemByAttribute", item, items()[2])
+		expectError(t, "GetItemByAttribute", b.GetItemByAttribute(typed, "rank", 6, &item), nosql.ErrNotFound)
+		results := []Item{}
+		expectNoError(t, "GetItemsByAttribute", b.GetItemsByAttribute(typed, "rank", 4, []string{}, &results))
+		expectIds(t, "GetItemsByAttribute", results, "d")
+		results = []Item{}
+		expectNoError(t, "GetItemsByAttribute", b.GetItemsByAttribute(typed, "status", "active", []string{"-rank"}, &results))
+		expectIds(t, "GetItemsByAttribute", results, "b", "a")
+	}},
+	{"GetItems", func(t *testing.T, b nosql.Backend, table_name string) {
+		results := []Item{}
+		expectNoError(t, "GetItems", b.GetItems(table_name, "", []string{"rank"}, &results))
+		expectIds(t, "GetItems", results, "a", "b", "c", "d", "e")
+		results = []Item{}
+		expectNoError(t, "GetItems", b.GetItems(table_name, "", []string{"-rank"}, &results))
+		expectIds(t, "GetItems", results, "e", "d", "c", "b", "a")
+	}},
+	{"GetItemsPage", func(t *testing.T, b nosql.Backend, table_name string) {
+		all := []Item{}
+		token := ""
+		for i := 0; ; i++ {
+			if i > 5 {
+				t.Fatalf("GetItemsPage returned more pages than expected")
+			}
+			page := []Item{}
+			next, err := b.GetItemsPage(table_name, "", []string{}, 2, token, &page)
+			expectNoError(t, "GetItemsPage", err)
+			if len(page) > 2 {
+				t.Fatalf("GetItemsPage returned %d items, expecting at most 2", len(page))
+			}
+			all = append(all, page...)
+			if len(next) == 0 {
+				break
+			}
+			token = next
+		}
+		expectIdSet(t, "GetItemsPage", all, "a", "b", "c", "d", "e")
+	}},
+	{"GetItemsPageInvalidToken", func(t *testing.T, b nosql.Backend, table_name string) {
+		page := []Item{}
+		_, err := b.GetItemsPage(table_name, "", []string{}, 2, "!", &page)
+		expectError(t, "GetItemsPage", err, nosql.ErrInvalidQuery)
+	}},
+	{"GetItemsByAttributeValuePage", func(t *testing.T, b nosql.Backend, table_name string) {
+		all := []Item{}
+		token := ""
+		for i := 0; ; i++ {
+			if i > 3 {
+				t.Fatalf("GetItemsByAttributeValuePage returned more pages than expected")
+			}
+			page := []Item{}
+			next, err := b.GetItemsByAttributeValuePage(table_name, "status", "inactive", []string{}, 1, token, &page)
+			expectNoError(t, "GetItemsByAttributeValuePage", err)
+			all = append(all, page...)
+			if len(next) == 0 {
+				break
+			}
+			token = next
+		}
+		expectIdSet(t, "GetItemsByAttributeValuePage", all, "c", "d")
+	}},
+	{"GetItemsPageSorted", func(t *testing.T, b nosql.Backend, table_name string) {
+		all := []Item{}
+		token := ""
+		for i := 0; ; i++ {
+			if i > 5 {
+				t.Fatalf("GetItemsPage returned more pages than expected")
+			}
+			page := []Item{}
+			next, err := b.GetItemsPage(table_name, "", []string{"-rank"}, 2, token, &page)
+			skipNotSupported(t, err)
+			expectNoError(t, "GetItemsPage", err)
+			all = append(all, page...)
+			if len(next) == 0 {
+				break
+			}
+			token = next
+		}
+		expectIds(t, "GetItemsPage", all, "e", "d", "c", "b", "a")
+	}},
+	{"GetItemsByAttributeValuePageSorted", func(t *testing.T, b nosql.Backend, table_name string) {
+		all := []Item{}
+		token := ""
+		for i := 0; ; i++ {
+			if i > 3 {
+				t.Fatalf("GetItemsByAttributeValuePage returned more pages than expected")
+			}
+			page := []Item{}
+			next, err := b.GetItemsByAttributeValuePage(table_name, "status", "inactive", []string{"-rank"}, 1, token, &page)
+			skipNotSupported(t, err)
+			expectNoError(t, "GetItemsByAttributeValuePage", err)
+			all = append(all, page...)
+			if len(next) == 0 {
+				break
+			}
+			token = next
+		}
+		expectIds(t, "GetItemsByAttributeValuePage", all, "d", "c")
+	}},
+	{"Scan", func(t *testing.T, b nosql.Backend, table_name string) {
+		results := collect(t, b.Scan(table_name, "", []string{}))
+		expectIdSet(t, "Scan", results, "a", "b", "c", "d", "e")
+	}},
+	{"Query", func(t *testing.T, b nosql.Backend, table_name string) {
+		results := collect(t, b.Query(table_name, "status", "active", []string{}))
+		expectIdSet(t, "Query", results, "a", "b")
+	}},
+	{"ScanSorted", func(t *testing.T, b nosql.Backend, table_name string) {
+		it := b.Scan(table_name, "", []string{"-rank"})
+		skipNotSupported(t, it.Err())
+		expectIds(t, "Scan", collect(t, it), "e", "d", "c", "b", "a")
+	}},
+	{"QuerySorted", func(t *testing.T, b nosql.Backend, table_name string) {
+		it := b.Query(table_name, "status", "inactive", []string{"-rank"})
+		skipNotSupported(t, it.Err())
+		expectIds(t, "Query", collect(t, it), "d", "c")
+	}},
+	{"Find", func(t *testing.T, b nosql.Backend, table_name string) {
+		for _, c := range findCases {
+			c := c
+			t.Run(c.name, func(t *testing.T) {
+				results := []Item{}
+				expectNoError(t, "Find", b.Find(table_name, c.filter, nil, &results))
+				expectIdSet(t, "Find", results, c.ids...)
+			})
+		}
+	}},
+	{"FindSortLimit", func(t *testing.T, b nosql.Backend, table_name string) {
+		results := []Item{}
+		opts := &nosql.FindOptions{SortFields: []string{"-rank"}, Limit: 2}
+		expectNoError(t, "Find", b.Find(table_name, nosql.Exists("status"), opts, &results))
+		expectIds(t, "Find", results, "e", "d")
+	}},
+	{"FindInvalid", func(t *testing.T, b nosql.Backend, table_name string) {
+		results := []Item{}
+		expectError(t, "Find", b.Find(table_name, nosql.And(), nil, &results), nosql.ErrInvalidQuery)
+	}},
+	{"InsertItem", func(t *testing.T, b nosql.Backend, table_name string) {
+		expected := Item{Id: "f", Name: "zeta", Status: "active", Rank: 6, Tags: []string{"y"}}
+		expectNoError(t, "InsertItem", b.InsertItem(table_name, expected))
+		item := Item{}
+		expectNoError(t, "GetItemById", b.GetItemById(table_name, "f", &item))
+		expectItem(t, "GetItemById", item, expected)
+	}},
+	{"InsertItemIfNotExists", func(t *testing.T, b nosql.Backend, table_name string) {
+		expected := Item{Id: "f", Name: "zeta", Status: "active", Rank: 6, Tags: []string{"y"}}
+		expectNoError(t, "InsertItemIfNotExists", b.InsertItemIfNotExists(table_name, expected))
+		existing := items()[0]
+		existing.Name = "ALPHA"
+		expectError(t, "InsertItemIfNotExists", b.InsertItemIfNotExists(table_name, existing), nosql.ErrConflict)
+		item := Item{}
+		expectNoError(t, "GetItemById", b.GetItemById(table_name, "a", &item))
+		expectItem(t, "GetItemById", item, items()[0])
+	}},
+	{"ReplaceItem", func(t *testing.T, b nosql.Backend, table_name string) {
+		expected := items()[0]
+		expected.Name = "ALPHA"
+		expectNoError(t, "ReplaceItem", b.ReplaceItem(table_name, expected))
+		item := Item{}
+		expectNoError(t, "GetItemById", b.GetItemById(table_name, "a", &item))
+		expectItem(t, "GetItemById", item, expected)
+		expectError(t, "ReplaceItem", b.ReplaceItem(table_name, Item{Id: "f", Name: "zeta"}), nosql.ErrNotFound)
+		expectError(t, "GetItemById", b.GetItemById(table_name, "f", &item), nosql.ErrNotFound)
+	}},
+	{"UpsertItem", func(t *testing.T, b nosql.Backend, table_name string) {
+		expected := items()[0]
+		expected.Name = "ALPHA"
+		expectNoError(t, "UpsertItem", b.UpsertItem(table_name, expected))
+		inserted := Item{Id: "f", Name: "zeta", Status: "active", Rank: 6, Tags: []string{"y"}}
+		expectNoError(t, "UpsertItem", b.UpsertItem(table_name, inserted))
+		item := Item{}
+		expectNoError(t, "GetItemById", b.GetItemById(table_name, "a", &item))
+		expectItem(t, "GetItemById", item, expected)
+		item = Item{}
+		expectNoError(t, "GetItemById", b.GetItemById(table_name, "f", &item))
+		expectItem(t, "GetItemById", item, inserted)
+	}},
+	{"InsertItems", func(t *testing.T, b nosql.Backend, table_name string) {
+		inserted := []Item{
+			Item{Id: "f", Name: "zeta", Status: "active", Rank: 6, Tags: []string{"y"}},
+			Item{Id: "g", Name: "eta", Status: "pending", Rank: 7, Tags: []string{"x"}},
+		}
+		results, err := b.InsertItems(table_name, inserted)
+		expectNoError(t, "InsertItems", err)
+		expectBulkResults(t, "InsertItems", results, "f", "g")
+		all := []Item{}
+		expectNoError(t, "GetItems", b.GetItems(table_name, "", []string{"rank"}, &all))
+		expectIds(t, "GetItems", all, "a", "b", "c", "d", "e", "f", "g")
+	}},
+	{"InsertItemsDuplicate", func(t *testing.T, b nosql.Backend, table_name string) {
+		duplicate := items()[0]
+		duplicate.Name = "ALPHA"
+		inserted := Item{Id: "f", Name: "zeta", Status: "active", Rank: 6, Tags: []string{"y"}}
+		results, err := b.InsertItems(table_name, []Item{duplicate, inserted})
+		expected := duplicate
+		if err != nil {
+			// MongoDB fails on the duplicate, and DynamoDB and memory overwrite it.
+			expectError(t, "InsertItems", err, nosql.ErrConflict)
+			if len(results) != 2 || !errors.Is(results[0].Err, nosql.ErrConflict) || results[1].Err != nil {
+				t.Fatalf("InsertItems returned results %+v, expecting only the first item to fail", results)
+			}
+			expected = items()[0]
+		} else {
+			expectBulkResults(t, "InsertItems", results, "a", "f")
+		}
+		item := Item{}
+		expectNoError(t, "GetItemById", b.GetItemById(table_name, "a", &item))
+		expectItem(t, "GetItemById", item, expected)
+		item = Item{}
+		expectNoError(t, "GetItemById", b.GetItemById(table_name, "f", &item))
+		expectItem(t, "GetItemById", item, inserted)
+	}},
+	{"PutItems", func(t *testing.T, b nosql.Backend, table_name string) {
+		replaced := items()[0]
+		replaced.Name = "ALPHA"
+		results, err := b.PutItems(table_name, []Item{replaced, Item{Id: "f", Name: "zeta", Status: "active", Rank: 6, Tags: []string{"y"}}})
+		expectNoError(t, "PutItems", err)
+		expectBulkResults(t, "PutItems", results, "a", "f")
+		item := Item{}
+		expectNoError(t, "GetItemById", b.GetItemById(table_name, "a", &item))
+		expectItem(t, "GetItemById", item, replaced)
+	}},
+	{"PutItemsInvalid", func(t *testing.T, b nosql.Backend, table_name string) {
+		results, err := b.PutItems(table_name, []Item{Item{Id: "f", Name: "zeta"}, Item{Name: "noid"}})
+		expectError(t, "PutItems", err, nosql.ErrInvalidQuery)
+		if len(results) != 2 || results[0].Err != nil || results[1].Index != 1 || !errors.Is(results[1].Err, nosql.ErrInvalidQuery) {
+			t.Fatalf("PutItems returned results %+v, expecting only the second item to fail", results)
+		}
+		item := Item{}
+		expectNoError(t, "GetItemById", b.GetItemById(table_name, "f", &item))
+	}},
+	{"DeleteItemsByIds", func(t *testing.T, b nosql.Backend, table_name string) {
+		results, err := b.DeleteItemsByIds(table_name, []string{"a", "z", "c"})
+		expectNoError(t, "DeleteItemsByIds", err)
+		expectBulkResults(t, "DeleteItemsByIds", results, "a", "z", "c")
+		all := []Item{}
+		expectNoError(t, "GetItems", b.GetItems(table_name, "", []string{"rank"}, &all))
+		expectIds(t, "GetItems", all, "b", "d", "e")
+	}},
+	{"UpdateItemById", func(t *testing.T, b nosql.Backend, table_name string) {
+		expectNoError(t, "UpdateItemById", b.UpdateItemById(table_name, "a", map[string]interface{}{"name": "ALPHA"}))
+		item := Item{}
+		expectNoError(t, "GetItemById", b.GetItemById(table_name, "a", &item))
+		expected := items()[0]
+		expected.Name = "ALPHA"
+		expectItem(t, "GetItemById", item, expected)
+	}},
+	{"UpdateItemByIdUnset", func(t *testing.T, b nosql.Backend, table_name string) {
+		expectNoError(t, "UpdateItemById", b.UpdateItemById(table_name, "a", map[string]interface{}{"tags": nil, "rank": 10}))
+		item := Item{}
+		expectNoError(t, "GetItemById", b.GetItemById(table_name, "a", &item))
+		expected := items()[0]
+		expected.Tags = nil
+		expected.Rank = 10
+		expectItem(t, "GetItemById", item, expected)
+	}},
+	{"UpdateItem", func(t *testing.T, b nosql.Backend, table_name string) {
+		update := nosql.NewUpdate().
+			Unset("status").
+			Increment("rank", 10).
+			Append("tags", "z").
+			AddToSet("labels", "new", "hot", "new").
+			SetIfNotExists("name", "ALPHA")
+		expectNoError(t, "UpdateItem", b.UpdateItem(table_name, "a", update))
+		update = nosql.NewUpdate().
+			Prepend("tags", "w").
+			RemoveFromSet("labels", "hot").
+			SetIfNotExists("status", "pending")
+		expectNoError(t, "UpdateItem", b.UpdateItem(table_name, "a", update))
+		item := Item{}
+		expectNoError(t, "GetItemById", b.GetItemById(table_name, "a", &item))
+		expected := items()[0]
+		expected.Status = "pending"
+		expected.Rank = 11
+		expected.Tags = []string{"w", "x", "y", "z"}
+		expected.Labels = []string{"new"}
+		expectItem(t, "GetItemById", item, expected)
+	}},
+	{"UpdateItemInvalid", func(t *testing.T, b nosql.Backend, table_name string) {
+		invalid := []*nosql.Update{
+			nosql.NewUpdate(),
+			nosql.NewUpdate().Increment("rank", "1"),
+			nosql.NewUpdate().Append("tags"),
+			nosql.NewUpdate().Set("name", "ALPHA").Unset("name"),
+			nosql.NewUpdate().Set("name", "ALPHA").ExpectVersion(0),
+		}
+		for _, update := range invalid {
+			expectError(t, "UpdateItem", b.UpdateItem(table_name, "a", update), nosql.ErrInvalidQuery)
+		}
+		item := Item{}
+		expectNoError(t, "GetItemById", b.GetItemById(table_name, "a", &item))
+		expectItem(t, "GetItemById", item, items()[0])
+	}},
+	{"VersionedUpdateItemById", func(t *testing.T, b nosql.Backend, table_name string) {
+		versioned := createTable(t, b, nosql.Table{Indexes: []string{"status"}, VersionAttribute: "version"})
+		expectNoError(t, "UpdateItemById", b.UpdateItemById(versioned, "a", map[string]interface{}{"name": "ALPHA", "version": 0}))
+		err := b.UpdateItemById(versioned, "a", map[string]interface{}{"name": "alpha", "version": 0})
+		var conflict *nosql.VersionConflictError
+		if !errors.As(err, &conflict) || !errors.Is(err, nosql.ErrConflict) {
+			t.Fatalf("UpdateItemById returned error %v, expecting a version conflict", err)
+		}
+		expectNoError(t, "UpdateItemById", b.UpdateItemById(versioned, "a", map[string]interface{}{"status": "inactive", "version": 1}))
+		item := Item{}
+		expectNoError(t, "GetItemById", b.GetItemById(versioned, "a", &item))
+		expected := items()[0]
+		expected.Name = "ALPHA"
+		expected.Status = "inactive"
+		expected.Version = 2
+		expectItem(t, "GetItemById", item, expected)
+		expectError(t, "UpdateItemById", b.UpdateItemById(versioned, "a", map[string]interface{}{"name": "alpha"}), nosql.ErrInvalidQuery)
+		expectError(t, "UpdateItemById", b.UpdateItemById(versioned, "z", map[string]interface{}{"name": "zeta", "version": 0}), nosql.ErrNotFound)
+	}},
+	{"VersionedSetIfNotExists", func(t *testing.T, b nosql.Backend, table_name string) {
+		versioned := createTable(t, b, nosql.Table{Indexes: []string{"status"}, VersionAttribute: "version"})
+		update := nosql.NewUpdate().SetIfNotExists("name", "ALPHA").SetIfNotExists("labels", []string{"x"})
+		expectNoError(t, "UpdateItem", b.UpdateItem(versioned, "a", update.ExpectVersion(0)))
+		update = nosql.NewUpdate().SetIfNotExists("status", "pending").SetIfNotExists("labels", []string{"y"})
+		expectError(t, "UpdateItem", b.UpdateItem(versioned, "a", update.ExpectVersion(0)), nosql.ErrConflict)
+		item := Item{}
+		expectNoError(t, "GetItemById", b.GetItemById(versioned, "a", &item))
+		expected := items()[0]
+		expected.Labels = []string{"x"}
+		expected.Version = 1
+		expectItem(t, "GetItemById", item, expected)
+	}},
+	{"VersionedReplaceItem", func(t *testing.T, b nosql.Backend, table_name string) {
+		versioned := createTable(t, b, nosql.Table{Indexes: []string{"status"}, VersionAttribute: "version"})
+		expected := items()[0]
+		expected.Name = "ALPHA"
+		expectNoError(t, "ReplaceItem", b.ReplaceItem(versioned, expected))
+		expectError(t, "ReplaceItem", b.ReplaceItem(versioned, expected), nosql.ErrConflict)
+		item := Item{}
+		expectNoError(t, "GetItemById", b.GetItemById(versioned, "a", &item))
+		expected.Version = 1
+		expectItem(t, "GetItemById", item, expected)
+		expectNoError(t, "ReplaceItem", b.ReplaceItem(versioned, expected))
+		expectError(t, "ReplaceItem", b.ReplaceItem(versioned, Item{Id: "z", Name: "zeta"}), nosql.ErrNotFound)
+	}},
+	{"WriteTransaction", func(t *testing.T, b nosql.Backend, table_name string) {
+		other := createTable(t, b, nosql.Table{Indexes: []string{"status"}})
+		tx := nosql.NewTransaction().
+			Put(table_name, Item{Id: "f", Name: "zeta", Status: "active", Rank: 6, Tags: []string{"y"}}).
+			Update(table_name, "a", nosql.NewUpdate().Increment("rank", -1)).If(nosql.Gte("rank", 1)).
+			Delete(other, "b").
+			ConditionCheck(other, "c", nosql.Eq("status", "inactive"))
+		skipNotSupported(t, b.WriteTransaction(tx))
+		results := []Item{}
+		expectNoError(t, "GetItems", b.GetItems(table_name, "", []string{"rank"}, &results))
+		expectIds(t, "GetItems", results, "a", "b", "c", "d", "e", "f")
+		expected := items()[0]
+		expected.Rank = 0
+		expectItem(t, "GetItems", results[0], expected)
+		results = []Item{}
+		expectNoError(t, "GetItems", b.GetItems(other, "", []string{"rank"}, &results))
+		expectIds(t, "GetItems", results, "a", "c", "d", "e")
+	}},
+	{"WriteTransactionCanceled", func(t *testing.T, b nosql.Backend, table_name string) {
+		tx := nosql.NewTransaction().
+			Put(table_name, Item{Id: "f", Name: "zeta", Status: "active", Rank: 6, Tags: []string{"y"}}).
+			Delete(table_name, "a").
+			ConditionCheck(table_name, "c", nosql.Eq("status", "active"))
+		err := b.WriteTransaction(tx)
+		skipNotSupported(t, err)
+		var canceled *nosql.TransactionError
+		if !errors.As(err, &canceled) || !errors.Is(err, nosql.ErrConflict) {
+			t.Fatalf("WriteTransaction returned error %v, expecting a canceled transaction", err)
+		}
+		if len(canceled.Failed) != 1 || canceled.Failed[0].Index != 2 || canceled.Failed[0].Id != "c" {
+			t.Fatalf("WriteTransaction returned failed operations %+v, expecting operation 2 on item \"c\"", canceled.Failed)
+		}
+		results := []Item{}
+		expectNoError(t, "GetItems", b.GetItems(table_name, "", []string{"rank"}, &results))
+		expectIds(t, "GetItems", results, "a", "b", "c", "d", "e")
+	}},
+	{"WriteTransactionInvalid", func(t *testing.T, b nosql.Backend, table_name string) {
+		invalid := []*nosql.Transaction{
+			nosql.NewTransaction(),
+			nosql.NewTransaction().Delete(table_name, "a").Update(table_name, "a", nosql.NewUpdate().Set("name", "ALPHA")),
+			nosql.NewTransaction().ConditionCheck(table_name, "a", nil),
+			nosql.NewTransaction().Update(table_name, "a", nosql.NewUpdate()),
+		}
+		for _, tx := range invalid {
+			err := b.WriteTransaction(tx)
+			skipNotSupported(t, err)
+			expectError(t, "WriteTransaction", err, nosql.ErrInvalidQuery)
+		}
+	}},
+	{"VersionedWriteTransaction", func(t *testing.T, b nosql.Backend, table_name string) {
+		versioned := createTable(t, b, nosql.Table{Indexes: []string{"status"}, VersionAttribute: "version"})
+		err := b.WriteTransaction(nosql.NewTransaction().Update(versioned, "a", nosql.NewUpdate().Set("name", "ALPHA").ExpectVersion(0)))
+		skipNotSupported(t, err)
+		expectNoError(t, "WriteTransaction", err)
+		err = b.WriteTransaction(nosql.NewTransaction().Update(versioned, "a", nosql.NewUpdate().Set("name", "alpha").ExpectVersion(0)))
+		var canceled *nosql.TransactionError
+		var conflict *nosql.VersionConflictError
+		if !errors.As(err, &canceled) || !errors.As(canceled.Failed[0].Err, &conflict) {
+			t.Fatalf("WriteTransaction returned error %v, expecting a version conflict", err)
+		}
+		err = b.WriteTransaction(nosql.NewTransaction().Update(versioned, "z", nosql.NewUpdate().Set("name", "zeta").ExpectVersion(0)))
+		expectError(t, "WriteTransaction", err, nosql.ErrNotFound)
+	}},
+	{"ReadTransaction", func(t *testing.T, b nosql.Backend, table_name string) {
+		other := createTable(t, b, nosql.Table{Indexes: []string{"status"}})
+		a, c, z := Item{}, Item{}, Item{}
+		err := b.ReadTransaction([]nosql.TransactionRead{
+			{Table: table_name, Id: "a", Item: &a},
+			{Table: other, Id: "c", Item: &c},
+			{Table: other, Id: "z", Item: &z},
+		})
+		skipNotSupported(t, err)
+		var missing *nosql.MissingItemsError
+		if !errors.As(err, &missing) || !reflect.DeepEqual(missing.Ids, []string{"z"}) {
+			t.Fatalf("ReadTransaction returned error %v, expecting item \"z\" to be missing", err)
+		}
+		expectItem(t, "ReadTransaction", a, items()[0])
+		expectItem(t, "ReadTransaction", c, items()[2])
+		expectError(t, "ReadTransaction", b.ReadTransaction([]nosql.TransactionRead{}), nosql.ErrInvalidQuery)
+	}},
+	{"RemoveItemById", func(t *testing.T, b nosql.Backend, table_name string) {
+		expectNoError(t, "RemoveItemById", b.RemoveItemById(table_name, "a"))
+		item := Item{}
+		expectError(t, "GetItemById", b.GetItemById(table_name, "a", &item), nosql.ErrNotFound)
+		expectNoError(t, "RemoveItemById", b.RemoveItemById(table_name, "a"))
+	}},
+	{"RemoveItemByAttributeValue", func(t *testing.T, b nosql.Backend, table_name string) {
+		expectNoError(t, "RemoveItemByAttributeValue", b.RemoveItemByAttributeValue(table_name, "status", "pending"))
+		results := []Item{}
+		expectNoError(t, "GetItems", b.GetItems(table_name, "", []string{"rank"}, &results))
+		expectIds(t, "GetItems", results, "a", "b", "c", "d")
+	}},
+	{"RemoveItemsByAttributeValue", func(t *testing.T, b nosql.Backend, table_name string) {
+		expectNoError(t, "RemoveItemsByAttributeValue", b.RemoveItemsByAttributeValue(table_name, "status", "active"))
+		results := []Item{}
+		expectNoError(t, "GetItems", b.GetItems(table_name, "", []string{"rank"}, &results))
+		expectIds(t, "GetItems", results, "c", "d", "e")
+	}},
+	{"RemoveAll", func(t *testing.T, b nosql.Backend, table_name string) {
+		expectNoError(t, "RemoveAll", b.RemoveAll(table_name))
+		results := []Item{}
+		expectNoError(t, "GetItems", b.GetItems(table_name, "", []string{}, &results))
+		expectIds(t, "GetItems", results)
+	}},
+	{"RemoveCompositeKey", func(t *testing.T, b nosql.Backend, table_name string) {
+		table := eventsTable()
+		table.Indexes = []string{"kind"}
+		events_table := createEmptyTable(t, b, table)
+		for _, e := range events() {
+			expectNoError(t, "InsertItem", b.InsertItem(events_table, e))
+		}
+		expectNoError(t, "RemoveItemByAttributeValue", b.RemoveItemByAttributeValue(events_table, "kind", "logout"))
+		expectEvents(t, b, events_table, "RemoveItemByAttributeValue", events()[0], events()[1], events()[2], events()[4])
+		expectNoError(t, "RemoveItemsByAttributeValue", b.RemoveItemsByAttributeValue(events_table, "kind", "view"))
+		expectEvents(t, b, events_table, "RemoveItemsByAttributeValue", events()[0], events()[4])
+		expectNoError(t, "RemoveAll", b.RemoveAll(events_table))
+		expectEvents(t, b, events_table, "RemoveAll")
+	}},
+	{"IdCompositeKey", func(t *testing.T, b nosql.Backend, table_name string) {
+		events_table := createEventsTable(t, b)
+		e := Event{}
+		expectError(t, "GetItemById", b.GetItemById(events_table, "u1", &e), nosql.ErrInvalidQuery)
+		expectError(t, "GetItemsByIds", b.GetItemsByIds(events_table, []string{"u1"}, []string{}, &[]Event{}), nosql.ErrInvalidQuery)
+		_, err := b.Exists(events_table, "u1")
+		expectError(t, "Exists", err, nosql.ErrInvalidQuery)
+		expectError(t, "UpdateItemById", b.UpdateItemById(events_table, "u1", map[string]interface{}{"kind": "x"}), nosql.ErrInvalidQuery)
+		expectError(t, "UpdateItem", b.UpdateItem(events_table, "u1", nosql.NewUpdate().Set("kind", "x")), nosql.ErrInvalidQuery)
+		expectError(t, "RemoveItemById", b.RemoveItemById(events_table, "u1"), nosql.ErrInvalidQuery)
+		_, err = b.DeleteItemsByIds(events_table, []string{"u1"})
+		expectError(t, "DeleteItemsByIds", err, nosql.ErrInvalidQuery)
+		if err := b.WriteTransaction(nosql.NewTransaction().Update(events_table, "u1", nosql.NewUpdate().Set("kind", "x"))); !errors.Is(err, nosql.ErrNotSupported) {
+			expectError(t, "WriteTransaction", err, nosql.ErrInvalidQuery)
+		}
+		if err := b.ReadTransaction([]nosql.TransactionRead{{Table: events_table, Id: "u1", Item: &e}}); !errors.Is(err, nosql.ErrNotSupported) {
+			expectError(t, "ReadTransaction", err, nosql.ErrInvalidQuery)
+		}
+		expectEvents(t, b, events_table, "IdCompositeKey", events()...)
+	}},
+	{"WriteCompositeKey", func(t *testing.T, b nosql.Backend, table_name string) {
+		events_table := createEventsTable(t, b)
+		_, err := b.PutItems(events_table, []Event{{User: "u1", Time: 1, Kind: "relogin"}, {User: "u3", Time: 1, Kind: "login"}})
+		expectNoError(t, "PutItems", err)
+		expectNoError(t, "UpsertItem", b.UpsertItem(events_table, Event{User: "u1", Time: 2, Kind: "edit"}))
+		expectNoError(t, "UpsertItem", b.UpsertItem(events_table, Event{User: "u3", Time: 2, Kind: "view"}))
+		expectNoError(t, "ReplaceItem", b.ReplaceItem(events_table, Event{User: "u2", Time: 2, Kind: "logout"}))
+		expectError(t, "ReplaceItem", b.ReplaceItem(events_table, Event{User: "u2", Time: 3, Kind: "view"}), nosql.ErrNotFound)
+		_, err = b.InsertItems(events_table, []Event{{User: "u4", Time: 1, Kind: "login"}})
+		expectNoError(t, "InsertItems", err)
+		expectEvents(t, b, events_table, "WriteCompositeKey",
+			Event{User: "u1", Time: 1, Kind: "relogin"},
+			Event{User: "u1", Time: 2, Kind: "edit"},
+			events()[2],
+			events()[3],
+			Event{User: "u2", Time: 2, Kind: "logout"},
+			Event{User: "u3", Time: 1, Kind: "login"},
+			Event{User: "u3", Time: 2, Kind: "view"},
+			Event{User: "u4", Time: 1, Kind: "login"},
+		)
+	}},
+	{"Canceled", func(t *testing.T, b nosql.Backend, table_name string) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		item := Item{}
+		expectError(t, "GetItemByIdContext", b.GetItemByIdContext(ctx, table_name, "a", &item), context.Canceled)
+		expectError(t, "InsertItemContext", b.InsertItemContext(ctx, table_name, items()[0]), context.Canceled)
+		it := b.ScanContext(ctx, table_name, "", []string{})
+		if it.Next(&item) {
+			t.Fatalf("ScanContext returned an item after the context was canceled")
+		}
+		expectError(t, "ScanContext", it.Err(), context.Canceled)
+		expectNoError(t, "Close", it.Close())
+	}},
+}
+
+// collect reads all the items of an iterator.
+func collect(t *testing.T, it nosql.Iterator) []Item {
+	t.Helper()
+	results := []Item{}
+	item := Item{}
+	for it.Next(&item) {
+		results = append(results, item)
+		item = Item{}
+	}
+	expectNoError(t, "Err", it.Err())
+	expectNoError(t, "Close", it.Close())
+	return results
+}
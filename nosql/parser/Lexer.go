@@ -0,0 +1,195 @@
+package parser
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenIdentifier
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenLeftParen
+	tokenRightParen
+	tokenComma
+)
+
+type token struct {
+	Type     tokenType
+	Text     string // the keyword, operator, or identifier, or the unquoted string
+	Position int    // the 1-based column of the first character
+	Quoted   bool   // true for identifiers quoted with backticks
+}
+
+func (t token) String() string {
+	switch t.Type {
+	case tokenEOF:
+		return "end of input"
+	case tokenString:
+		return "string " + quote(t.Text)
+	case tokenIdentifier:
+		if t.Quoted {
+			return "`" + t.Text + "`"
+		}
+	}
+	return quote(t.Text)
+}
+
+// keyword returns true if the token is the given keyword, ignoring case.
+func (t token) keyword(k string) bool {
+	return t.Type == tokenIdentifier && !t.Quoted && strings.EqualFold(t.Text, k)
+}
+
+func quote(s string) string {
+	return "\"" + strings.Replace(strings.Replace(s, "\\", "\\\\", -1), "\"", "\\\"", -1) + "\""
+}
+
+// lex splits an expression into tokens.
+func lex(expression string) ([]token, error) {
+	tokens := []token{}
+	i := 0
+	for i < len(expression) {
+		r, size := utf8.DecodeRuneInString(expression[i:])
+		position := utf8.RuneCountInString(expression[:i]) + 1
+		switch {
+		case r == utf8.RuneError && size == 1:
+			return nil, &SyntaxError{Position: position, Message: "invalid UTF-8 encoding"}
+		case unicode.IsSpace(r):
+			i += size
+		case r == '(':
+			tokens = append(tokens, token{Type: tokenLeftParen, Text: "(", Position: position})
+			i += size
+		case r == ')':
+			tokens = append(tokens, token{Type: tokenRightParen, Text: ")", Position: position})
+			i += size
+		case r == ',':
+			tokens = append(tokens, token{Type: tokenComma, Text: ",", Position: position})
+			i += size
+		case r == '=' || r == '!' || r == '<' || r == '>':
+			op := string(r)
+			if i+1 < len(expression) {
+				switch two := expression[i : i+2]; two {
+				case "==", "!=", "<>", "<=", ">=":
+					op = two
+				}
+			}
+			if op == "!" {
+				return nil, &SyntaxError{Position: position, Message: "unexpected \"!\", did you mean \"!=\"?"}
+			}
+			tokens = append(tokens, token{Type: tokenOperator, Text: op, Position: position})
+			i += len(op)
+		case r == '"' || r == '\'':
+			s, n, err := lexString(expression[i:], position)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{Type: tokenString, Text: s, Position: position})
+			i += n
+		case r == '`':
+			end := strings.IndexRune(expression[i+1:], '`')
+			if end < 0 {
+				return nil, &SyntaxError{Position: position, Message: "unterminated quoted identifier"}
+			}
+			if end == 0 {
+				return nil, &SyntaxError{Position: position, Message: "empty quoted identifier"}
+			}
+			tokens = append(tokens, token{Type: tokenIdentifier, Text: expression[i+1 : i+1+end], Position: position, Quoted: true})
+			i += end + 2
+		case r == '-' || r == '+' || r == '.' || unicode.IsDigit(r):
+			n := lexNumber(expression[i:])
+			if n == 0 {
+				return nil, &SyntaxError{Position: position, Message: "unexpected " + quote(string(r))}
+			}
+			tokens = append(tokens, token{Type: tokenNumber, Text: expression[i : i+n], Position: position})
+			i += n
+		case r == '_' || unicode.IsLetter(r):
+			n := strings.IndexFunc(expression[i:], func(r rune) bool {
+				return !(r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r))
+			})
+			if n < 0 {
+				n = len(expression) - i
+			}
+			tokens = append(tokens, token{Type: tokenIdentifier, Text: expression[i : i+n], Position: position})
+			i += n
+		default:
+			return nil, &SyntaxError{Position: position, Message: "unexpected " + quote(string(r))}
+		}
+	}
+	tokens = append(tokens, token{Type: tokenEOF, Position: utf8.RuneCountInString(expression) + 1})
+	return tokens, nil
+}
+
+// lexString reads a quoted string with backslash escapes and returns the unquoted
+// value and the number of bytes read.
+func lexString(s string, position int) (string, int, error) {
+	quote_rune := rune(s[0])
+	var b strings.Builder
+	escaped := false
+	for i, r := range s[1:] {
+		switch {
+		case escaped:
+			switch r {
+			case 'n':
+				b.WriteRune('\n')
+			case 't':
+				b.WriteRune('\t')
+			case 'r':
+				b.WriteRune('\r')
+			case '\\', '"', '\'':
+				b.WriteRune(r)
+			default:
+				return "", 0, &SyntaxError{Position: position + utf8.RuneCountInString(s[:i+1]) - 1, Message: "invalid escape sequence \"\\" + string(r) + "\""}
+			}
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == quote_rune:
+			return b.String(), i + 2, nil
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return "", 0, &SyntaxError{Position: position, Message: "unterminated string"}
+}
+
+// lexNumber returns the length of the number at the start of s, or zero.
+func lexNumber(s string) int {
+	i := 0
+	if i < len(s) && (s[i] == '-' || s[i] == '+') {
+		i++
+	}
+	digits := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+		digits++
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+			digits++
+		}
+	}
+	if digits == 0 {
+		return 0
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		j := i + 1
+		if j < len(s) && (s[j] == '-' || s[j] == '+') {
+			j++
+		}
+		k := j
+		for k < len(s) && s[k] >= '0' && s[k] <= '9' {
+			k++
+		}
+		if k > j {
+			i = k
+		}
+	}
+	return i
+}
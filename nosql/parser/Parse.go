@@ -0,0 +1,277 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/spatialcurrent/go-nosql/nosql"
+)
+
+// Parse parses a textual query into a nosql.Filter, such as
+//
+//	status = "active" AND created > "2024-01-01"
+//	(age >= 21 OR guardian EXISTS) AND NOT role IN ("admin", "owner")
+//	name BEGINS_WITH "Jo" AND score BETWEEN 10 AND 20 AND tags CONTAINS "go"
+//
+// Keywords are case-insensitive.  Strings use double or single quotes, and attribute
+// names that collide with keywords can be quoted with backticks.  Malformed input
+// returns a *SyntaxError with the position of the problem.
+func Parse(expression string) (nosql.Filter, error) {
+	tokens, err := lex(expression)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().Type == tokenEOF {
+		return nil, p.errorf(p.peek(), "empty expression")
+	}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.Type != tokenEOF {
+		return nil, p.errorf(t, "unexpected "+t.String())
+	}
+	return f, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.Type != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, message string) error {
+	return &SyntaxError{Position: t.Position, Message: message}
+}
+
+func (p *parser) expected(what string) error {
+	t := p.peek()
+	return p.errorf(t, "expected "+what+", found "+t.String())
+}
+
+func (p *parser) parseOr() (nosql.Filter, error) {
+	f, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	filters := []nosql.Filter{f}
+	for p.peek().keyword("OR") {
+		p.next()
+		f, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return nosql.Or(filters...), nil
+}
+
+func (p *parser) parseAnd() (nosql.Filter, error) {
+	f, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	filters := []nosql.Filter{f}
+	for p.peek().keyword("AND") {
+		p.next()
+		f, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return nosql.And(filters...), nil
+}
+
+func (p *parser) parseNot() (nosql.Filter, error) {
+	if p.peek().keyword("NOT") {
+		p.next()
+		f, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return nosql.Not(f), nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (nosql.Filter, error) {
+	if p.peek().Type == tokenLeftParen {
+		p.next()
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().Type != tokenRightParen {
+			return nil, p.expected("\")\"")
+		}
+		p.next()
+		return f, nil
+	}
+	return p.parsePredicate()
+}
+
+func (p *parser) parsePredicate() (nosql.Filter, error) {
+	t := p.peek()
+	if t.Type != tokenIdentifier || (!t.Quoted && isKeyword(t.Text)) {
+		return nil, p.expected("attribute name")
+	}
+	p.next()
+	return p.parseCondition(t.Text)
+}
+
+// parseCondition parses the operator and operands that follow an attribute name.
+func (p *parser) parseCondition(attribute_name string) (nosql.Filter, error) {
+	op := p.peek()
+	switch {
+	case op.Type == tokenOperator:
+		p.next()
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		switch op.Text {
+		case "=", "==":
+			return nosql.Eq(attribute_name, v), nil
+		case "!=", "<>":
+			return nosql.Ne(attribute_name, v), nil
+		case "<":
+			return nosql.Lt(attribute_name, v), nil
+		case "<=":
+			return nosql.Lte(attribute_name, v), nil
+		case ">":
+			return nosql.Gt(attribute_name, v), nil
+		case ">=":
+			return nosql.Gte(attribute_name, v), nil
+		}
+	case op.keyword("IN"):
+		p.next()
+		if p.peek().Type != tokenLeftParen {
+			return nil, p.expected("\"(\"")
+		}
+		p.next()
+		values := []interface{}{}
+		for {
+			v, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+			if p.peek().Type == tokenComma {
+				p.next()
+				continue
+			}
+			if p.peek().Type != tokenRightParen {
+				return nil, p.expected("\",\" or \")\"")
+			}
+			p.next()
+			break
+		}
+		return nosql.In(attribute_name, values...), nil
+	case op.keyword("BETWEEN"):
+		p.next()
+		lower, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peek().keyword("AND") {
+			return nil, p.expected("AND")
+		}
+		p.next()
+		upper, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		return nosql.Between(attribute_name, lower, upper), nil
+	case op.keyword("BEGINS_WITH"):
+		p.next()
+		v := p.peek()
+		if v.Type != tokenString {
+			return nil, p.expected("string")
+		}
+		p.next()
+		return nosql.BeginsWith(attribute_name, v.Text), nil
+	case op.keyword("CONTAINS"):
+		p.next()
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		return nosql.Contains(attribute_name, v), nil
+	case op.keyword("EXISTS"):
+		p.next()
+		return nosql.Exists(attribute_name), nil
+	case op.keyword("NOT"):
+		p.next()
+		next := p.peek()
+		if !(next.keyword("EXISTS") || next.keyword("IN") || next.keyword("BETWEEN") || next.keyword("CONTAINS") || next.keyword("BEGINS_WITH")) {
+			return nil, p.expected("EXISTS, IN, BETWEEN, CONTAINS, or BEGINS_WITH")
+		}
+		f, err := p.parseCondition(attribute_name)
+		if err != nil {
+			return nil, err
+		}
+		return nosql.Not(f), nil
+	}
+	return nil, p.expected("operator")
+}
+
+func (p *parser) parseValue() (interface{}, error) {
+	t := p.peek()
+	switch {
+	case t.Type == tokenString:
+		p.next()
+		return t.Text, nil
+	case t.Type == tokenNumber:
+		p.next()
+		if i, err := strconv.ParseInt(t.Text, 10, 64); err == nil {
+			return i, nil
+		}
+		f, err := strconv.ParseFloat(t.Text, 64)
+		if err != nil {
+			return nil, p.errorf(t, "invalid number "+t.String())
+		}
+		return f, nil
+	case t.keyword("true"):
+		p.next()
+		return true, nil
+	case t.keyword("false"):
+		p.next()
+		return false, nil
+	case t.keyword("null"):
+		p.next()
+		return nil, nil
+	}
+	return nil, p.expected("value")
+}
+
+var keywords = []string{"AND", "OR", "NOT", "IN", "BETWEEN", "BEGINS_WITH", "CONTAINS", "EXISTS", "TRUE", "FALSE", "NULL"}
+
+func isKeyword(s string) bool {
+	for _, k := range keywords {
+		if strings.EqualFold(s, k) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,16 @@
+package parser
+
+import (
+	"fmt"
+)
+
+// SyntaxError is returned for malformed expressions.  Position is the 1-based
+// column of the offending character.
+type SyntaxError struct {
+	Position int
+	Message  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Position, e.Message)
+}
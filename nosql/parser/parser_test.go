@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+import (
+	"github.com/spatialcurrent/go-nosql/nosql"
+)
+
+func TestParse(t *testing.T) {
+	for _, c := range []struct {
+		expression string
+		expected   nosql.Filter
+	}{
+		{`status = "active"`, nosql.Eq("status", "active")},
+		{`rank >= 3 and rank < 5.5`, nosql.And(nosql.Gte("rank", int64(3)), nosql.Lt("rank", 5.5))},
+		{`a = 1 OR b != 'x' AND NOT c EXISTS`, nosql.Or(nosql.Eq("a", int64(1)), nosql.And(nosql.Ne("b", "x"), nosql.Not(nosql.Exists("c"))))},
+		{`(a = true OR b = null) AND c.d <> false`, nosql.And(nosql.Or(nosql.Eq("a", true), nosql.Eq("b", nil)), nosql.Ne("c.d", false))},
+		{`role NOT IN ("admin", "owner")`, nosql.Not(nosql.In("role", "admin", "owner"))},
+		{`name BEGINS_WITH "Jo" AND score BETWEEN 10 AND 20`, nosql.And(nosql.BeginsWith("name", "Jo"), nosql.Between("score", int64(10), int64(20)))},
+		{`tags contains "go"`, nosql.Contains("tags", "go")},
+		{"`and` = \"a\\\"b\"", nosql.Eq("and", "a\"b")},
+	} {
+		f, err := Parse(c.expression)
+		if err != nil {
+			t.Fatalf("Parse(%q) returned error: %v", c.expression, err)
+		}
+		if !reflect.DeepEqual(f, c.expected) {
+			t.Fatalf("Parse(%q) returned %+v, expecting %+v", c.expression, f, c.expected)
+		}
+	}
+}
+
+func TestParseSyntaxError(t *testing.T) {
+	for _, c := range []struct {
+		expression string
+		position   int
+		message    string
+	}{
+		{``, 1, "empty expression"},
+		{`a =`, 4, "expected value, found end of input"},
+		{`a = "x`, 5, "unterminated string"},
+		{`a = 1 b`, 7, `unexpected "b"`},
+		{`(a = 1`, 7, `expected ")", found end of input`},
+		{`a IN 1`, 6, `expected "(", found "1"`},
+		{`a ! 1`, 3, `did you mean "!="?`},
+		{`a = "\q"`, 6, `invalid escape sequence "\q"`},
+		{`= 1`, 1, "expected attribute name"},
+		{`a BETWEEN 1 OR 2`, 13, "expected AND"},
+		{`a NOT = 1`, 7, "expected EXISTS, IN, BETWEEN, CONTAINS, or BEGINS_WITH"},
+		{`a BEGINS_WITH 1`, 15, "expected string"},
+		{`é = @`, 5, `unexpected "@"`},
+	} {
+		_, err := Parse(c.expression)
+		se := &SyntaxError{}
+		if !errors.As(err, &se) {
+			t.Fatalf("Parse(%q) returned error %v, expecting a syntax error", c.expression, err)
+		}
+		if se.Position != c.position || !strings.Contains(se.Message, c.message) {
+			t.Fatalf("Parse(%q) returned %q, expecting %q at position %d", c.expression, err, c.message, c.position)
+		}
+	}
+}
+
+// FuzzParse checks that parsing never panics, and that malformed input returns a
+// syntax error with a position inside the expression or just after it.
+func FuzzParse(f *testing.F) {
+	for _, s := range []string{
+		`status = "active" AND created > "2024-01-01"`,
+		`(age >= 21 OR guardian EXISTS) AND NOT role IN ("admin", 'owner')`,
+		`name begins_with "Jo" and score between 10 and 20.5 and tags contains "go"`,
+		"`not` NOT IN (1, 2.5e3, true, null)",
+		`a = "é\n"`,
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, expression string) {
+		filter, err := Parse(expression)
+		if err != nil {
+			se := &SyntaxError{}
+			if !errors.As(err, &se) {
+				t.Fatalf("Parse(%q) returned error %v, expecting a syntax error", expression, err)
+			}
+			if se.Position < 1 || se.Position > utf8.RuneCountInString(expression)+1 {
+				t.Fatalf("Parse(%q) returned position %d outside of the expression", expression, se.Position)
+			}
+			return
+		}
+		if filter == nil {
+			t.Fatalf("Parse(%q) returned no filter and no error", expression)
+		}
+	})
+}